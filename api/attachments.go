@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+)
+
+type Attachment struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+// AttachmentFile is the downloaded content of an attachment
+type AttachmentFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// AttachmentURL returns the path Outline uses to reference an attachment
+// from document text
+func AttachmentURL(attachmentID string) string {
+	return "/api/attachments.redirect?id=" + url.QueryEscape(attachmentID)
+}
+
+func (c *client) CreateAttachment(name string, contentType string, data []byte, documentID string) (*Attachment, error) {
+	return c.createAttachment(name, contentType, data, documentID, "")
+}
+
+// createAttachment registers and uploads a file. preset selects Outline's
+// limits for the upload, such as workspaceImport; empty means a document
+// attachment.
+func (c *client) createAttachment(name string, contentType string, data []byte, documentID string, preset string) (*Attachment, error) {
+	payload := struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+		Size        int    `json:"size"`
+		DocumentID  string `json:"documentId,omitempty"`
+		Preset      string `json:"preset,omitempty"`
+	}{
+		Name:        name,
+		ContentType: contentType,
+		Size:        len(data),
+		DocumentID:  documentID,
+		Preset:      preset,
+	}
+
+	var response struct {
+		Data struct {
+			UploadURL  string            `json:"uploadUrl"`
+			Form       map[string]string `json:"form"`
+			Attachment Attachment        `json:"attachment"`
+		} `json:"data"`
+	}
+	held, err := c.send("attachments.create", payload, &response, "")
+	if err != nil {
+		return nil, err
+	}
+	if held {
+		return &Attachment{Name: name, ContentType: contentType, Size: int64(len(data))}, nil
+	}
+
+	if err := c.uploadFile(response.Data.UploadURL, response.Data.Form, name, contentType, data); err != nil {
+		return nil, err
+	}
+
+	return &response.Data.Attachment, nil
+}
+
+// uploadFile posts data to the presigned upload URL returned by
+// attachments.create, as a multipart form with the file field last
+func (c *client) uploadFile(uploadURL string, fields map[string]string, name string, contentType string, data []byte) error {
+	target, err := c.resolve(uploadURL)
+	if err != nil {
+		return fmt.Errorf("parsing upload URL: %w", err)
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return fmt.Errorf("writing form field: %w", err)
+		}
+	}
+	fw, err := mw.CreatePart(fileHeader(name, contentType))
+	if err != nil {
+		return fmt.Errorf("writing form file: %w", err)
+	}
+	if _, err := fw.Write(data); err != nil {
+		return fmt.Errorf("writing form file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("writing form: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", target.String(), &buf)
+	if err != nil {
+		return fmt.Errorf("creating upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading upload response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError("uploading file", resp, respBody)
+	}
+
+	return nil
+}
+
+func (c *client) DownloadAttachment(attachmentID string) (*AttachmentFile, error) {
+	req, err := http.NewRequest("GET", c.redirectURL(attachmentID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("attachments.redirect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError("attachments.redirect", resp, data)
+	}
+
+	file := &AttachmentFile{
+		Name:        path.Base(resp.Request.URL.Path),
+		ContentType: resp.Header.Get("Content-Type"),
+		Data:        data,
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		file.Name = params["filename"]
+	}
+	return file, nil
+}
+
+// redirectURL returns the absolute attachments.redirect URL of an attachment
+func (c *client) redirectURL(attachmentID string) string {
+	return normalizeURL(c.config.OutlineURL) + AttachmentURL(attachmentID)
+}
+
+// resolve interprets ref relative to the configured Outline URL
+func (c *client) resolve(ref string) (*url.URL, error) {
+	base, err := url.Parse(normalizeURL(c.config.OutlineURL) + "/")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	return base.ResolveReference(u), nil
+}
+
+func fileHeader(name string, contentType string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": "file", "filename": name})},
+		"Content-Type":        {contentType},
+	}
+}
+
+func (c *client) DeleteAttachment(attachmentID string) error {
+	payload := struct {
+		ID string `json:"id"`
+	}{
+		ID: attachmentID,
+	}
+	return c.Call("attachments.delete", payload, nil)
+}
+
+// ResolveAttachmentURL returns the URL attachments.redirect sends clients
+// to, typically a short-lived signed storage URL, without following it
+func (c *client) ResolveAttachmentURL(attachmentID string) (string, error) {
+	endpoint := c.redirectURL(attachmentID)
+
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+
+	noRedirect := *c.httpClient
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("attachments.redirect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return "", fmt.Errorf("reading redirect location: %w", err)
+		}
+		return location.String(), nil
+	case resp.StatusCode == http.StatusOK:
+		// Served directly rather than redirected
+		return endpoint, nil
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", responseError("attachments.redirect", resp, body)
+	}
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// cache keeps successful responses to read-only RPCs for a short time, so
+// commands that look the same document up more than once only fetch it once
+type cache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+func newCache(ttl time.Duration) *cache {
+	return &cache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *cache) middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method := rpcMethod(req)
+		if method == "" {
+			return next.RoundTrip(req)
+		}
+		if !IsReadOnly(method) {
+			// Anything that may change Outline makes cached reads stale
+			c.clear()
+			return next.RoundTrip(req)
+		}
+
+		key, ok := cacheKey(req)
+		if !ok {
+			return next.RoundTrip(req)
+		}
+		if resp, ok := c.get(key, req); ok {
+			return resp, nil
+		}
+
+		resp, err := next.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			return resp, err
+		}
+		body, err := drain(&resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.put(key, resp, body)
+		return resp, nil
+	})
+}
+
+// cacheKey identifies a call by its URL and request body
+func cacheKey(req *http.Request) (string, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req.Method + " " + req.URL.String(), true
+	}
+	if req.GetBody == nil {
+		return "", false
+	}
+	r, err := req.GetBody()
+	if err != nil {
+		return "", false
+	}
+	defer r.Close()
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", false
+	}
+	return req.Method + " " + req.URL.String() + "\n" + string(body), true
+}
+
+func (c *cache) get(key string, req *http.Request) (*http.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}, true
+}
+
+func (c *cache) put(key string, resp *http.Response, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{
+		status:  resp.StatusCode,
+		header:  resp.Header.Clone(),
+		body:    body,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,381 @@
+package api
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// redacted replaces secrets in recorded interactions
+const redacted = "[REDACTED]"
+
+// secretFields lists JSON keys whose values are scrubbed from recorded bodies
+var secretFields = map[string]bool{
+	"token":        true,
+	"apikey":       true,
+	"api_key":      true,
+	"accesstoken":  true,
+	"refreshtoken": true,
+	"password":     true,
+	"secret":       true,
+}
+
+// secretParams lists query parameters, such as those of presigned storage
+// URLs, whose values are scrubbed from recorded URLs
+var secretParams = []string{"signature", "credential", "token", "key"}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request with secrets scrubbed
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedResponse is a response with secrets scrubbed
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	RecordedBody
+}
+
+// RecordedBody holds a body as text, or base64 when it isn't UTF-8
+type RecordedBody struct {
+	Body     string `json:"body,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+func newRecordedBody(data []byte) RecordedBody {
+	if utf8.Valid(data) {
+		return RecordedBody{Body: string(data)}
+	}
+	return RecordedBody{Body: base64.StdEncoding.EncodeToString(data), Encoding: "base64"}
+}
+
+func (b RecordedBody) bytes() ([]byte, error) {
+	if b.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(b.Body)
+	}
+	return []byte(b.Body), nil
+}
+
+// Recorder is an http.RoundTripper that saves every interaction to a
+// directory as numbered JSON files, scrubbing credentials on the way
+type Recorder struct {
+	dir  string
+	next http.RoundTripper
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecorder records the interactions of next into dir
+func NewRecorder(dir string, next http.RoundTripper) *Recorder {
+	return &Recorder{dir: dir, next: next}
+}
+
+// WithRecorder records every request the client makes into dir
+func WithRecorder(dir string) Option {
+	return func(c *client) {
+		c.base = NewRecorder(dir, c.base)
+	}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := drain(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("recording request: %w", err)
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := drain(&resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("recording response: %w", err)
+	}
+
+	secret := bearerToken(req.Header)
+	in := Interaction{
+		Request: RecordedRequest{
+			Method:       req.Method,
+			URL:          scrubURL(req.URL),
+			Header:       RedactHeaders(req.Header),
+			RecordedBody: newRecordedBody(scrubBody(reqBody, secret)),
+		},
+		Response: RecordedResponse{
+			Status:       resp.StatusCode,
+			Header:       scrubResponseHeader(resp.Header),
+			RecordedBody: newRecordedBody(scrubBody(respBody, secret)),
+		},
+	}
+	if err := r.save(req.URL, in); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) save(u *url.URL, in Interaction) error {
+	data, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding interaction: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.dir, 0700); err != nil {
+		return fmt.Errorf("creating cassette directory: %w", err)
+	}
+	r.seq++
+	name := fmt.Sprintf("%04d-%s.json", r.seq, interactionName(u))
+	if err := os.WriteFile(filepath.Join(r.dir, name), data, 0600); err != nil {
+		return fmt.Errorf("writing interaction: %w", err)
+	}
+	return nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from interactions
+// saved by a Recorder, without touching the network
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer loads the interactions recorded in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in %s", dir)
+	}
+	sort.Strings(names)
+
+	r := &Replayer{}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading interaction: %w", err)
+		}
+		var in Interaction
+		if err := json.Unmarshal(data, &in); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Base(name), err)
+		}
+		r.interactions = append(r.interactions, in)
+	}
+	r.used = make([]bool, len(r.interactions))
+	return r, nil
+}
+
+// WithReplay serves every request from the interactions recorded in dir.
+// A cassette that can't be loaded makes every request fail.
+func WithReplay(dir string) Option {
+	return func(c *client) {
+		replayer, err := NewReplayer(dir)
+		if err != nil {
+			c.base = failingTransport{err}
+			return
+		}
+		c.base = replayer
+	}
+}
+
+// RoundTrip answers with the first unused interaction for the same method
+// and URL, preferring one whose request body also matches
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := drain(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("replaying request: %w", err)
+	}
+	target := scrubURL(req.URL)
+	scrubbed := string(scrubBody(body, bearerToken(req.Header)))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := -1
+	for i, in := range r.interactions {
+		if r.used[i] || in.Request.Method != req.Method || !samePath(in.Request.URL, target) {
+			continue
+		}
+		recorded, _ := in.Request.bytes()
+		if string(recorded) == scrubbed {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, target)
+	}
+	r.used[match] = true
+
+	resp := r.interactions[match].Response
+	data, err := resp.bytes()
+	if err != nil {
+		return nil, fmt.Errorf("decoding recorded body: %w", err)
+	}
+	header := resp.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+type failingTransport struct {
+	err error
+}
+
+func (f failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+// drain reads a body fully and replaces it with a re-readable copy
+func drain(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, nil
+}
+
+func bearerToken(h http.Header) string {
+	_, token, _ := strings.Cut(h.Get("Authorization"), " ")
+	return token
+}
+
+// scrubBody masks secret JSON fields and any occurrence of the API key
+func scrubBody(data []byte, secret string) []byte {
+	if secret != "" {
+		data = bytes.ReplaceAll(data, []byte(secret), []byte(redacted))
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	if !scrubJSON(v) {
+		return data
+	}
+	scrubbed, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return scrubbed
+}
+
+// scrubJSON masks secret fields in a decoded JSON value in place and
+// reports whether anything changed
+func scrubJSON(v any) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if secretFields[strings.ToLower(k)] {
+				if child != redacted {
+					v[k] = redacted
+					changed = true
+				}
+				continue
+			}
+			changed = scrubJSON(child) || changed
+		}
+	case []any:
+		for _, child := range v {
+			changed = scrubJSON(child) || changed
+		}
+	}
+	return changed
+}
+
+func scrubResponseHeader(h http.Header) http.Header {
+	scrubbed := RedactHeaders(h)
+	if location := scrubbed.Get("Location"); location != "" {
+		if u, err := url.Parse(location); err == nil {
+			scrubbed.Set("Location", scrubURL(u))
+		}
+	}
+	return scrubbed
+}
+
+// scrubURL masks signatures and credentials in query parameters
+func scrubURL(u *url.URL) string {
+	copied := *u
+	query := copied.Query()
+	for k := range query {
+		lower := strings.ToLower(k)
+		for _, secret := range secretParams {
+			if strings.Contains(lower, secret) {
+				query.Set(k, redacted)
+			}
+		}
+	}
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
+
+// samePath compares URLs ignoring the host, so a cassette recorded against
+// one server can be replayed for another
+func samePath(a string, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return ua.Path == ub.Path && ua.Query().Encode() == ub.Query().Encode()
+}
+
+// interactionName names a recording after the RPC method it called
+func interactionName(u *url.URL) string {
+	name := strings.TrimPrefix(u.Path, "/api/")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.Trim(name, "/"))
+	if name == "" {
+		return "request"
+	}
+	return name
+}
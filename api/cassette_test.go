@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/config"
+	"outline-cli/outlinetest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	srv := outlinetest.NewServer()
+	doc := srv.AddDocument(api.Document{Title: "Doc", Text: "Recorded text"})
+	att := srv.AddAttachment("notes.txt", "text/plain", []byte("attached"), doc.ID)
+	cfg := srv.Config()
+
+	session := func(c api.Client) (string, string) {
+		t.Helper()
+		got, err := c.GetDocument(doc.ID)
+		if err != nil {
+			t.Fatalf("GetDocument: %v", err)
+		}
+		if err := c.UpdateDocument(doc.ID, "Changed text"); err != nil {
+			t.Fatalf("UpdateDocument: %v", err)
+		}
+		file, err := c.DownloadAttachment(att.ID)
+		if err != nil {
+			t.Fatalf("DownloadAttachment: %v", err)
+		}
+		return got.Text, string(file.Data)
+	}
+
+	text, data := session(api.DefaultClientFactory(cfg, api.WithRecorder(dir)))
+	srv.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Errorf("expected 4 recorded interactions, got %d", len(files))
+	}
+	for _, f := range files {
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(content), cfg.APIKey) {
+			t.Errorf("%s leaks the API key", filepath.Base(f))
+		}
+	}
+	if !strings.HasSuffix(files[0], "0001-documents.info.json") {
+		t.Errorf("expected recordings named after the RPC method, got %s", filepath.Base(files[0]))
+	}
+
+	// The server is gone; everything must come from the cassette
+	replayed, replayedData := session(api.DefaultClientFactory(cfg, api.WithReplay(dir)))
+	if replayed != text || replayedData != data {
+		t.Errorf("replay returned %q and %q, recorded %q and %q", replayed, replayedData, text, data)
+	}
+
+	if _, err := api.DefaultClientFactory(cfg, api.WithReplay(dir)).ListCollections(); err == nil {
+		t.Error("expected an unrecorded request to fail")
+	}
+}
+
+func TestRecorderScrubsSecrets(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [], "token": "session-token"}`))
+	}))
+	defer server.Close()
+
+	c := api.DefaultClientFactory(&config.Config{APIKey: "secret-key", OutlineURL: server.URL}, api.WithRecorder(dir))
+	if _, err := c.QueryDocuments(api.ListOptions{}); err != nil {
+		t.Fatalf("QueryDocuments: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 recording, got %d", len(files))
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-key", "session-token"} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("recording leaks %q: %s", secret, content)
+		}
+	}
+	if !strings.Contains(string(content), `"Bearer [REDACTED]"`) {
+		t.Errorf("expected a redacted Authorization header, got %s", content)
+	}
+}
@@ -1,126 +1,185 @@
 package api
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"outline-cli/config"
+	"outline-cli/diff"
 	"strings"
+	"time"
 )
 
 type Client interface {
-	GetDocument(docID string, verbose bool) (*Document, error)
-	UpdateDocument(docID string, content string, verbose bool) error
-	ListDocuments(verbose bool) ([]Document, error)
-	CreateDocument(title string, text string, collectionId string, verbose bool) (*Document, error)
+	GetDocument(docID string) (*Document, error)
+	UpdateDocument(docID string, content string) error
+	ListDocuments() ([]Document, error)
+	CreateDocument(title string, text string, collectionId string, parentDocumentID string) (*Document, error)
+	ListCollections() ([]Collection, error)
+	SearchDocuments(query string) ([]SearchResult, error)
+	SearchTitles(query string) ([]Document, error)
+	CreateAttachment(name string, contentType string, data []byte, documentID string) (*Attachment, error)
+	DownloadAttachment(attachmentID string) (*AttachmentFile, error)
+	DeleteAttachment(attachmentID string) error
+	ResolveAttachmentURL(attachmentID string) (string, error)
+	QueryDocuments(opts ListOptions) ([]Document, error)
+	ListEvents(opts ListOptions) ([]Event, error)
+	CollectionDocuments(collectionID string) ([]NavigationNode, error)
+	DeleteDocument(docID string) error
+	MoveDocument(docID string, collectionID string, parentDocumentID string) error
+	RenameDocument(docID string, title string) error
+	GetSharedDocument(shareID string, docID string) (*Document, error)
+	ExportCollection(collectionID string, format string) (*FileOperation, error)
+	ImportCollection(attachmentID string, format string) (*FileOperation, error)
+	GetFileOperation(id string) (*FileOperation, error)
+	DownloadFileOperation(id string, w io.Writer) error
+	CreateImportAttachment(name string, data []byte) (*Attachment, error)
+	// Call sends payload to any RPC method, for endpoints without a
+	// dedicated method
+	Call(method string, payload any, out any) error
+}
+
+// Option configures a client built by a ClientFactory
+type Option func(*client)
+
+// WithLogger sets the logger used for request diagnostics
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *client) {
+		c.logger = logger
+	}
 }
 
 // ClientFactory is a function type that creates new API clients
-type ClientFactory func(*config.Config) Client
+type ClientFactory func(*config.Config, ...Option) Client
 
-// DefaultClientFactory creates real API clients
-var DefaultClientFactory ClientFactory = func(cfg *config.Config) Client {
-	return &client{
-		httpClient: &http.Client{},
-		config:     cfg,
+// sharedTransport is reused by every client so bulk operations keep
+// connections alive instead of dialing for each request
+var sharedTransport = newSharedTransport()
+
+func newSharedTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = 32
+	return t
+}
+
+// DefaultClientFactory creates real API clients. Every request they make
+// goes through one middleware chain around a transport built from the
+// config; see transport and NewTransport.
+var DefaultClientFactory ClientFactory = func(cfg *config.Config, opts ...Option) Client {
+	c := &client{
+		config:  cfg,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+	}
+	base, err := NewTransport(cfg.TransportConfig)
+	if err != nil {
+		c.base = failingTransport{fmt.Errorf("configuring HTTP transport: %w", err)}
+	} else {
+		c.base = base
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	if cfg.InsecureSkipVerify {
+		c.logger.Warn("TLS certificate verification is disabled by insecure_skip_verify; " +
+			"anyone on the network path can read and alter traffic to Outline, including the API key")
+	}
+	c.httpClient = &http.Client{Transport: c.transport()}
+	return c
 }
 
-// Rename the Client struct to client (private)
 type client struct {
+	// httpClient sends requests through the middleware chain around base
 	httpClient *http.Client
+	base       http.RoundTripper
 	config     *config.Config
+	logger     *slog.Logger
+	dryRun     DryRunFunc
+
+	middleware []Middleware
+	retries    int
+	backoff    time.Duration
+	rateLimit  time.Duration
+	cacheTTL   time.Duration
+	metrics    *Metrics
 }
 
 type Document struct {
-	ID      string `json:"id"`
-	Title   string `json:"title"`
-	Text    string `json:"text"`
-	Version int    `json:"version"`
+	ID               string    `json:"id"`
+	URLID            string    `json:"urlId"`
+	Title            string    `json:"title"`
+	Text             string    `json:"text"`
+	CollectionID     string    `json:"collectionId"`
+	ParentDocumentID string    `json:"parentDocumentId"`
+	Version          int       `json:"version"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	Icon             string    `json:"icon,omitempty"`
+	Emoji            string    `json:"emoji,omitempty"`
+}
+
+type Collection struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URLID       string `json:"urlId"`
+	Color       string `json:"color,omitempty"`
+	Icon        string `json:"icon,omitempty"`
+	// Permission is the default access of team members, "read" or
+	// "read_write"; empty for private collections
+	Permission string `json:"permission,omitempty"`
+}
+
+// SearchResult is a single hit returned by documents.search
+type SearchResult struct {
+	Ranking  float64  `json:"ranking"`
+	Context  string   `json:"context"`
+	Document Document `json:"document"`
 }
 
 func normalizeURL(baseURL string) string {
 	return strings.TrimRight(baseURL, "/")
 }
 
-func (c *client) GetDocument(docID string, verbose bool) (*Document, error) {
-	url := fmt.Sprintf("%s/api/documents.info", normalizeURL(c.config.OutlineURL))
-	if verbose {
-		fmt.Printf("Making request to: %s\n", url)
-	}
-
-	// Create request body with document ID
+func (c *client) GetDocument(docID string) (*Document, error) {
 	payload := struct {
 		ID string `json:"id"`
 	}{
 		ID: docID,
 	}
 
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Content-Type", "application/json")
-
-	if verbose {
-		fmt.Printf("Request headers:\n")
-		for k, v := range req.Header {
-			fmt.Printf("  %s: %s\n", k, v)
-		}
-		fmt.Printf("Request body: %s\n", string(body))
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
+	var response struct {
+		Data Document `json:"data"`
 	}
-
-	if verbose {
-		fmt.Printf("Response status: %s\n", resp.Status)
-		fmt.Printf("Response body: %s\n", string(respBody))
+	if err := c.Call("documents.info", payload, &response); err != nil {
+		return nil, err
 	}
+	return &response.Data, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		var apiError struct {
-			Error   string `json:"error"`
-			Message string `json:"message"`
-		}
-		if err := json.Unmarshal(respBody, &apiError); err == nil {
-			return nil, fmt.Errorf("API error: %s - %s", apiError.Error, apiError.Message)
-		}
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(respBody))
+// GetSharedDocument fetches a document through a share link. An empty
+// docID means the shared document itself rather than one nested below it.
+func (c *client) GetSharedDocument(shareID string, docID string) (*Document, error) {
+	payload := struct {
+		ID      string `json:"id,omitempty"`
+		ShareID string `json:"shareId"`
+	}{
+		ID:      docID,
+		ShareID: shareID,
 	}
 
 	var response struct {
 		Data Document `json:"data"`
 	}
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, fmt.Errorf("decoding response (status %d): %w\nBody: %s", resp.StatusCode, err, string(respBody))
+	if err := c.Call("documents.info", payload, &response); err != nil {
+		return nil, err
 	}
-
 	return &response.Data, nil
 }
 
-func (c *client) UpdateDocument(docID string, content string, verbose bool) error {
-	url := fmt.Sprintf("%s/api/documents.update", normalizeURL(c.config.OutlineURL))
-
-	// Include publish flag in the update payload
+func (c *client) UpdateDocument(docID string, content string) error {
 	payload := struct {
 		ID      string `json:"id"`
 		Text    string `json:"text"`
@@ -130,160 +189,117 @@ func (c *client) UpdateDocument(docID string, content string, verbose bool) erro
 		Text:    content,
 		Publish: true,
 	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("marshaling payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	if verbose {
-		fmt.Printf("Making update request to: %s\n", url)
-		fmt.Printf("Request payload: %s\n", string(body))
-	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("reading response body: %w", err)
-	}
-
-	if verbose {
-		fmt.Printf("Response status: %s\n", resp.Status)
-		fmt.Printf("Response body: %s\n", string(respBody))
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(respBody))
-	}
-
-	return nil
+	_, err := c.send("documents.update", payload, nil, c.updateDiff(docID, content))
+	return err
 }
 
-func (c *client) ListDocuments(verbose bool) ([]Document, error) {
-	url := fmt.Sprintf("%s/api/documents.list", normalizeURL(c.config.OutlineURL))
-	if verbose {
-		fmt.Printf("Making request to: %s\n", url)
-	}
+// listPageSize is the number of items requested per page by list methods,
+// Outline's maximum
+const listPageSize = 100
 
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-	req.Header.Set("Accept", "application/json")
+// ListDocuments returns every published document, fetching page by page
+func (c *client) ListDocuments() ([]Document, error) {
+	return listAll[Document](c, "documents.list")
+}
 
-	if verbose {
-		fmt.Printf("Request headers:\n")
-		for k, v := range req.Header {
-			fmt.Printf("  %s: %s\n", k, v)
+// listAll requests pages of a list method until a short page comes back
+func listAll[T any](c *client, method string) ([]T, error) {
+	items := []T{}
+	for {
+		payload := struct {
+			Offset int `json:"offset"`
+			Limit  int `json:"limit"`
+		}{
+			Offset: len(items),
+			Limit:  listPageSize,
+		}
+		var response struct {
+			Data []T `json:"data"`
+		}
+		if err := c.Call(method, payload, &response); err != nil {
+			return nil, err
+		}
+		items = append(items, response.Data...)
+		if len(response.Data) < listPageSize {
+			return items, nil
 		}
 	}
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
-	}
-
-	if verbose {
-		fmt.Printf("Response status: %s\n", resp.Status)
-		fmt.Printf("Response body: %s\n", string(body))
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
-	}
-
-	var response struct {
-		Data []Document `json:"data"`
-	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("decoding response (status %d): %w\nBody: %s", resp.StatusCode, err, string(body))
-	}
-
-	return response.Data, nil
 }
 
-func (c *client) CreateDocument(title string, text string, collectionId string, verbose bool) (*Document, error) {
-	url := fmt.Sprintf("%s/api/documents.create", normalizeURL(c.config.OutlineURL))
-
+// CreateDocument publishes a new document, nested below parentDocumentID
+// unless it is empty
+func (c *client) CreateDocument(title string, text string, collectionId string, parentDocumentID string) (*Document, error) {
 	payload := struct {
-		Title        string `json:"title"`
-		Text         string `json:"text"`
-		CollectionId string `json:"collectionId"`
-		Publish      bool   `json:"publish"`
+		Title            string `json:"title"`
+		Text             string `json:"text"`
+		CollectionId     string `json:"collectionId"`
+		ParentDocumentID string `json:"parentDocumentId,omitempty"`
+		Publish          bool   `json:"publish"`
 	}{
-		Title:        title,
-		Text:         text,
-		CollectionId: collectionId,
-		Publish:      true,
-	}
-
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling payload: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+		Title:            title,
+		Text:             text,
+		CollectionId:     collectionId,
+		ParentDocumentID: parentDocumentID,
+		Publish:          true,
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.config.APIKey))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-
-	if verbose {
-		fmt.Printf("Making create request to: %s\n", url)
-		fmt.Printf("Request payload: %s\n", string(body))
+	var response struct {
+		Data Document `json:"data"`
 	}
-
-	resp, err := c.httpClient.Do(req)
+	held, err := c.send("documents.create", payload, &response, diff.Unified("/dev/null", title, "", text))
 	if err != nil {
-		return nil, fmt.Errorf("executing request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("reading response body: %w", err)
+	if held {
+		return &Document{Title: title, Text: text, CollectionID: collectionId, ParentDocumentID: parentDocumentID}, nil
 	}
+	return &response.Data, nil
+}
 
-	if verbose {
-		fmt.Printf("Response status: %s\n", resp.Status)
-		fmt.Printf("Response body: %s\n", string(respBody))
-	}
+// ListCollections returns every collection, fetching page by page
+func (c *client) ListCollections() ([]Collection, error) {
+	return listAll[Collection](c, "collections.list")
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(respBody))
+func (c *client) SearchDocuments(query string) ([]SearchResult, error) {
+	payload := struct {
+		Query string `json:"query"`
+	}{
+		Query: query,
 	}
 
 	var response struct {
-		Data Document `json:"data"`
+		Data []SearchResult `json:"data"`
 	}
-	if err := json.Unmarshal(respBody, &response); err != nil {
-		return nil, fmt.Errorf("decoding response: %w", err)
+	if err := c.Call("documents.search", payload, &response); err != nil {
+		return nil, err
 	}
+	return response.Data, nil
+}
 
-	return &response.Data, nil
+// SearchTitles returns every document whose title contains query, fetching
+// page by page
+func (c *client) SearchTitles(query string) ([]Document, error) {
+	docs := []Document{}
+	for {
+		payload := struct {
+			Query  string `json:"query"`
+			Offset int    `json:"offset"`
+			Limit  int    `json:"limit"`
+		}{
+			Query:  query,
+			Offset: len(docs),
+			Limit:  listPageSize,
+		}
+		var response struct {
+			Data []Document `json:"data"`
+		}
+		if err := c.Call("documents.search_titles", payload, &response); err != nil {
+			return nil, err
+		}
+		docs = append(docs, response.Data...)
+		if len(response.Data) < listPageSize {
+			return docs, nil
+		}
+	}
 }
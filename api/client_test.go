@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"outline-cli/config"
+)
+
+func TestDryRunHoldsBackMutations(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": Document{ID: "doc-1", Text: "old line\n"},
+		})
+	}))
+	defer server.Close()
+
+	var held []Mutation
+	c := DefaultClientFactory(&config.Config{
+		APIKey:     "secret-key",
+		OutlineURL: server.URL,
+	}, WithDryRun(func(m Mutation) {
+		held = append(held, m)
+	}))
+
+	if err := c.UpdateDocument("doc-1", "new line\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Only the read used to build the diff may reach the server
+	if len(calls) != 1 || calls[0] != "/api/documents.info" {
+		t.Errorf("expected only documents.info to be called, got %v", calls)
+	}
+	if len(held) != 1 {
+		t.Fatalf("expected 1 held mutation, got %d", len(held))
+	}
+
+	m := held[0]
+	if m.Method != "documents.update" {
+		t.Errorf("expected documents.update, got %s", m.Method)
+	}
+	if strings.Contains(m.Header.Get("Authorization"), "secret-key") {
+		t.Error("held mutation leaked the API key")
+	}
+	if !strings.Contains(m.Diff, "-old line\n+new line\n") {
+		t.Errorf("expected diff against remote text, got %q", m.Diff)
+	}
+	if !strings.Contains(string(m.Body), `"text":"new line\n"`) {
+		t.Errorf("expected request body with new text, got %s", m.Body)
+	}
+}
+
+func TestDryRunAllowsReads(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	c := DefaultClientFactory(&config.Config{OutlineURL: server.URL}, WithDryRun(func(Mutation) {
+		t.Error("read-only call was held back")
+	}))
+
+	if _, err := c.QueryDocuments(ListOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
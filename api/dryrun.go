@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"outline-cli/diff"
+)
+
+// Mutation describes a state-changing request that dry-run mode held back
+type Mutation struct {
+	// Method is the RPC method, such as documents.update
+	Method string
+	URL    string
+	// Header holds the request headers with credentials redacted
+	Header http.Header
+	Body   []byte
+	// Diff is a unified diff of the document text the request would change,
+	// if any
+	Diff string
+}
+
+// DryRunFunc receives each mutation a dry-run client declines to send
+type DryRunFunc func(Mutation)
+
+// WithDryRun makes mutating calls report their request to fn instead of
+// sending it. Read-only calls are still made.
+func WithDryRun(fn DryRunFunc) Option {
+	return func(c *client) {
+		c.dryRun = fn
+	}
+}
+
+// readMethods lists the RPC methods known to only read. They are sent in
+// dry-run mode, may be retried after a server error and may be cached.
+var readMethods = map[string]bool{
+	"attachments.redirect":    true,
+	"auth.config":             true,
+	"auth.info":               true,
+	"collections.documents":   true,
+	"collections.info":        true,
+	"collections.list":        true,
+	"collections.memberships": true,
+	"documents.archived":      true,
+	"documents.drafts":        true,
+	"documents.export":        true,
+	"documents.info":          true,
+	"documents.list":          true,
+	"documents.search":        true,
+	"documents.search_titles": true,
+	"documents.viewed":        true,
+	"events.list":             true,
+	"fileOperations.info":     true,
+	"fileOperations.list":     true,
+	"fileOperations.redirect": true,
+	"groups.info":             true,
+	"groups.list":             true,
+	"revisions.info":          true,
+	"revisions.list":          true,
+	"shares.info":             true,
+	"shares.list":             true,
+	"stars.list":              true,
+	"users.info":              true,
+	"users.list":              true,
+	"views.list":              true,
+}
+
+// IsReadOnly reports whether an RPC method is known to only read from
+// Outline
+func IsReadOnly(method string) bool {
+	return readMethods[method]
+}
+
+// IsMutating reports whether an RPC method may change state in Outline.
+// Methods not known to be read-only are assumed to.
+func IsMutating(method string) bool {
+	return !IsReadOnly(method)
+}
+
+// holdBack reports whether req should not be sent because the client is in
+// dry-run mode, handing it to the dry-run callback if so
+func (c *client) holdBack(method string, req *http.Request, body []byte, textDiff string) bool {
+	if c.dryRun == nil || !IsMutating(method) {
+		return false
+	}
+
+	c.dryRun(Mutation{
+		Method: method,
+		URL:    req.URL.String(),
+		Header: RedactHeaders(req.Header),
+		Body:   body,
+		Diff:   textDiff,
+	})
+	return true
+}
+
+// updateDiff compares a document's current text with the text an update
+// would set
+func (c *client) updateDiff(docID string, text string) string {
+	if c.dryRun == nil {
+		return ""
+	}
+	current, err := c.GetDocument(docID)
+	if err != nil {
+		c.logger.Warn("fetching document for dry-run diff", "id", docID, "error", err)
+		return ""
+	}
+	return diff.Unified("remote/"+docID, "local/"+docID, current.Text, text)
+}
+
+// FormatMutation renders m as a human-readable request description
+func FormatMutation(m Mutation) string {
+	var sb strings.Builder
+	sb.WriteString("POST " + m.URL + "\n")
+	for k, v := range m.Header {
+		sb.WriteString(k + ": " + strings.Join(v, ", ") + "\n")
+	}
+
+	var pretty bytes.Buffer
+	if json.Indent(&pretty, m.Body, "", "  ") == nil {
+		sb.WriteString("\n" + pretty.String() + "\n")
+	} else if len(m.Body) > 0 {
+		sb.WriteString("\n" + string(m.Body) + "\n")
+	}
+
+	if m.Diff != "" {
+		sb.WriteString("\n" + m.Diff)
+	}
+	return sb.String()
+}
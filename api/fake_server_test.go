@@ -0,0 +1,203 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/outlinetest"
+)
+
+func TestClientDocumentsAgainstFakeServer(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	coll := srv.AddCollection("Runbooks")
+	parent := srv.AddDocument(api.Document{Title: "Databases", Text: "Overview", CollectionID: coll.ID})
+	c := api.DefaultClientFactory(srv.Config())
+
+	doc, err := c.CreateDocument("Failover", "Step 1", coll.ID, "")
+	if err != nil {
+		t.Fatalf("CreateDocument: %v", err)
+	}
+	if doc.ID == "" || doc.Version != 1 {
+		t.Errorf("expected a new document at version 1, got %+v", doc)
+	}
+
+	if err := c.UpdateDocument(doc.ID, "Step 1\nStep 2"); err != nil {
+		t.Fatalf("UpdateDocument: %v", err)
+	}
+	got, err := c.GetDocument(doc.URLID)
+	if err != nil {
+		t.Fatalf("GetDocument by url-id: %v", err)
+	}
+	if got.Text != "Step 1\nStep 2" || got.Version != 2 {
+		t.Errorf("expected updated text at version 2, got %q at %d", got.Text, got.Version)
+	}
+	if revs := srv.Revisions(doc.ID); len(revs) != 2 {
+		t.Errorf("expected 2 revisions, got %d", len(revs))
+	}
+
+	if err := c.RenameDocument(doc.ID, "DB Failover"); err != nil {
+		t.Fatalf("RenameDocument: %v", err)
+	}
+	if err := c.MoveDocument(doc.ID, coll.ID, parent.ID); err != nil {
+		t.Fatalf("MoveDocument: %v", err)
+	}
+	tree, err := c.CollectionDocuments(coll.ID)
+	if err != nil {
+		t.Fatalf("CollectionDocuments: %v", err)
+	}
+	if len(tree) != 1 || len(tree[0].Children) != 1 || tree[0].Children[0].Title != "DB Failover" {
+		t.Errorf("expected renamed document nested under its parent, got %+v", tree)
+	}
+
+	results, err := c.SearchDocuments("failover")
+	if err != nil {
+		t.Fatalf("SearchDocuments: %v", err)
+	}
+	if len(results) != 1 || results[0].Document.ID != doc.ID {
+		t.Errorf("expected search to find the document, got %+v", results)
+	}
+
+	titled, err := c.SearchTitles("failover")
+	if err != nil {
+		t.Fatalf("SearchTitles: %v", err)
+	}
+	if len(titled) != 1 || titled[0].ID != doc.ID {
+		t.Errorf("expected title search to find the document, got %+v", titled)
+	}
+
+	recent, err := c.QueryDocuments(api.ListOptions{Sort: "updatedAt", Direction: "DESC", Limit: 1})
+	if err != nil {
+		t.Fatalf("QueryDocuments: %v", err)
+	}
+	if len(recent) != 1 || recent[0].ID != doc.ID {
+		t.Errorf("expected the most recently updated document first, got %+v", recent)
+	}
+
+	shared, err := c.GetSharedDocument(srv.Share(parent.ID), doc.ID)
+	if err != nil {
+		t.Fatalf("GetSharedDocument: %v", err)
+	}
+	if shared.ID != doc.ID {
+		t.Errorf("expected nested shared document, got %s", shared.ID)
+	}
+
+	if err := c.DeleteDocument(parent.ID); err != nil {
+		t.Fatalf("DeleteDocument: %v", err)
+	}
+	if docs, err := c.ListDocuments(); err != nil || len(docs) != 0 {
+		t.Errorf("expected deleting the parent to delete its child, got %d documents, %v", len(docs), err)
+	}
+
+	events, err := c.ListEvents(api.ListOptions{CollectionID: coll.ID})
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+	if len(events) == 0 || events[0].Name != "documents.delete" {
+		t.Errorf("expected newest event to be a delete, got %+v", events)
+	}
+}
+
+func TestClientAttachmentsAgainstFakeServer(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	doc := srv.AddDocument(api.Document{Title: "Diagrams"})
+	c := api.DefaultClientFactory(srv.Config())
+
+	att, err := c.CreateAttachment("diagram.png", "image/png", []byte("png bytes"), doc.ID)
+	if err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+	if stored, ok := srv.Attachment(att.ID); !ok || string(stored.Data) != "png bytes" {
+		t.Fatalf("expected upload to reach the server, got %+v", stored)
+	}
+
+	file, err := c.DownloadAttachment(att.ID)
+	if err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	if file.Name != "diagram.png" || file.ContentType != "image/png" || string(file.Data) != "png bytes" {
+		t.Errorf("unexpected download %q %q %q", file.Name, file.ContentType, file.Data)
+	}
+
+	location, err := c.ResolveAttachmentURL(att.ID)
+	if err != nil {
+		t.Fatalf("ResolveAttachmentURL: %v", err)
+	}
+	if !strings.HasPrefix(location, srv.URL+"/files/") {
+		t.Errorf("expected storage URL, got %s", location)
+	}
+
+	if err := c.DeleteAttachment(att.ID); err != nil {
+		t.Fatalf("DeleteAttachment: %v", err)
+	}
+	if _, err := c.DownloadAttachment(att.ID); err == nil {
+		t.Error("expected deleted attachment to be gone")
+	}
+}
+
+func TestClientReportsAuthAndServerErrors(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	doc := srv.AddDocument(api.Document{Title: "Doc", Text: "Original"})
+
+	cfg := srv.Config()
+	cfg.APIKey = "wrong-key"
+	if _, err := api.DefaultClientFactory(cfg).GetDocument(doc.ID); err == nil || !strings.Contains(err.Error(), "authentication_required") {
+		t.Errorf("expected authentication error, got %v", err)
+	}
+
+	c := api.DefaultClientFactory(srv.Config())
+	srv.Fail("documents.update", outlinetest.Fault{Status: http.StatusServiceUnavailable, Times: 1})
+	if err := c.UpdateDocument(doc.ID, "Changed"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected injected 503, got %v", err)
+	}
+	if got, _ := srv.Document(doc.ID); got.Text != "Original" {
+		t.Errorf("failed update changed the document to %q", got.Text)
+	}
+
+	// The fault only applied once
+	if err := c.UpdateDocument(doc.ID, "Changed"); err != nil {
+		t.Fatalf("unexpected error after fault: %v", err)
+	}
+	calls := srv.Calls()
+	if calls[len(calls)-1] != "documents.update" {
+		t.Errorf("expected last call to be documents.update, got %v", calls)
+	}
+}
+
+func TestClientListsEveryPage(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	total := outlinetest.MaxLimit + outlinetest.DefaultLimit
+	coll := srv.AddCollection("Runbooks")
+	for i := 0; i < total; i++ {
+		srv.AddDocument(api.Document{Title: fmt.Sprintf("Doc %d", i), CollectionID: coll.ID})
+	}
+	for i := 0; i < outlinetest.DefaultLimit+5; i++ {
+		srv.AddCollection(fmt.Sprintf("Collection %d", i))
+	}
+	c := api.DefaultClientFactory(srv.Config())
+
+	docs, err := c.ListDocuments()
+	if err != nil {
+		t.Fatalf("ListDocuments: %v", err)
+	}
+	if len(docs) != total {
+		t.Errorf("expected all %d documents, got %d", total, len(docs))
+	}
+	collections, err := c.ListCollections()
+	if err != nil {
+		t.Fatalf("ListCollections: %v", err)
+	}
+	if len(collections) != outlinetest.DefaultLimit+6 {
+		t.Errorf("expected all %d collections, got %d", outlinetest.DefaultLimit+6, len(collections))
+	}
+}
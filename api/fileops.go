@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// FileOperation is an export or import Outline runs in the background
+type FileOperation struct {
+	ID string `json:"id"`
+	// Type is export or import
+	Type   string `json:"type"`
+	State  string `json:"state"`
+	Format string `json:"format"`
+	Name   string `json:"name"`
+	// Error explains why an operation in the error state failed
+	Error        string `json:"error"`
+	Size         int64  `json:"size"`
+	CollectionID string `json:"collectionId"`
+}
+
+// File operation states
+const (
+	FileOperationCreating  = "creating"
+	FileOperationUploading = "uploading"
+	FileOperationComplete  = "complete"
+	FileOperationError     = "error"
+	FileOperationExpired   = "expired"
+)
+
+// Archive formats of collections.export and collections.import
+const (
+	FormatMarkdown = "outline-markdown"
+	FormatJSON     = "json"
+	FormatHTML     = "html"
+)
+
+// importPreset is the attachments.create preset for archives handed to
+// collections.import, which allows larger files than document attachments
+const importPreset = "workspaceImport"
+
+// ExportCollection starts exporting a collection, or the whole workspace
+// when collectionID is empty, as an archive in format
+func (c *client) ExportCollection(collectionID string, format string) (*FileOperation, error) {
+	method := "collections.export"
+	payload := map[string]string{"id": collectionID, "format": format}
+	if collectionID == "" {
+		method = "collections.export_all"
+		payload = map[string]string{"format": format}
+	}
+	return c.startFileOperation(method, payload, "export", format)
+}
+
+// ImportCollection starts importing an archive uploaded with
+// CreateImportAttachment
+func (c *client) ImportCollection(attachmentID string, format string) (*FileOperation, error) {
+	payload := map[string]string{"attachmentId": attachmentID, "format": format}
+	return c.startFileOperation("collections.import", payload, "import", format)
+}
+
+func (c *client) startFileOperation(method string, payload any, opType string, format string) (*FileOperation, error) {
+	var response struct {
+		Data struct {
+			FileOperation FileOperation `json:"fileOperation"`
+		} `json:"data"`
+	}
+	held, err := c.send(method, payload, &response, "")
+	if err != nil {
+		return nil, err
+	}
+	if held {
+		return &FileOperation{Type: opType, Format: format}, nil
+	}
+	return &response.Data.FileOperation, nil
+}
+
+func (c *client) GetFileOperation(id string) (*FileOperation, error) {
+	payload := struct {
+		ID string `json:"id"`
+	}{
+		ID: id,
+	}
+
+	var response struct {
+		Data FileOperation `json:"data"`
+	}
+	if err := c.Call("fileOperations.info", payload, &response); err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
+// DownloadFileOperation writes the archive of a completed export to w.
+// Outline redirects to the file in storage, which is streamed rather than
+// held in memory.
+func (c *client) DownloadFileOperation(id string, w io.Writer) error {
+	body, err := json.Marshal(struct {
+		ID string `json:"id"`
+	}{
+		ID: id,
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint("fileOperations.redirect"), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fileOperations.redirect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return responseError("fileOperations.redirect", resp, data)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("downloading archive: %w", err)
+	}
+	return nil
+}
+
+// CreateImportAttachment uploads an archive for ImportCollection
+func (c *client) CreateImportAttachment(name string, data []byte) (*Attachment, error) {
+	return c.createAttachment(name, "application/zip", data, "", importPreset)
+}
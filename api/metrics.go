@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MethodStats summarizes the calls made to one RPC method
+type MethodStats struct {
+	Method string
+	Calls  int
+	// Errors counts calls that failed on the network or got an error status
+	Errors int
+	// Total is the time spent in calls, including retries
+	Total time.Duration
+}
+
+// Metrics collects per-method statistics from every client it is given to
+// with WithMetrics. It is safe for concurrent use.
+type Metrics struct {
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+// NewMetrics returns an empty collector
+func NewMetrics() *Metrics {
+	return &Metrics{methods: make(map[string]*MethodStats)}
+}
+
+// Snapshot returns the statistics collected so far, sorted by method
+func (m *Metrics) Snapshot() []MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]MethodStats, 0, len(m.methods))
+	for _, s := range m.methods {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// Reset discards the statistics collected so far
+func (m *Metrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.methods)
+}
+
+func (m *Metrics) record(method string, d time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.methods[method]
+	if !ok {
+		s = &MethodStats{Method: method}
+		m.methods[method] = s
+	}
+	s.Calls++
+	s.Total += d
+	if failed {
+		s.Errors++
+	}
+}
+
+func (m *Metrics) middleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method := rpcMethod(req)
+		if method == "" {
+			method = "storage"
+		}
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		failed := err != nil || resp.StatusCode < 200 || resp.StatusCode >= 400
+		m.record(method, time.Since(start), failed)
+		return resp, err
+	})
+}
@@ -1,19 +1,130 @@
 package api
 
+import "io"
+
+// MockClient is a Client whose methods call the matching func field. Calls
+// to a method whose field is nil panic.
 type MockClient struct {
-	GetDocumentFunc    func(docID string, verbose bool) (*Document, error)
-	UpdateDocumentFunc func(docID string, content string, verbose bool) error
-	ListDocumentsFunc  func(verbose bool) ([]Document, error)
+	GetDocumentFunc            func(docID string) (*Document, error)
+	UpdateDocumentFunc         func(docID string, content string) error
+	ListDocumentsFunc          func() ([]Document, error)
+	CreateDocumentFunc         func(title string, text string, collectionId string, parentDocumentID string) (*Document, error)
+	ListCollectionsFunc        func() ([]Collection, error)
+	SearchDocumentsFunc        func(query string) ([]SearchResult, error)
+	SearchTitlesFunc           func(query string) ([]Document, error)
+	CreateAttachmentFunc       func(name string, contentType string, data []byte, documentID string) (*Attachment, error)
+	DownloadAttachmentFunc     func(attachmentID string) (*AttachmentFile, error)
+	DeleteAttachmentFunc       func(attachmentID string) error
+	ResolveAttachmentURLFunc   func(attachmentID string) (string, error)
+	QueryDocumentsFunc         func(opts ListOptions) ([]Document, error)
+	ListEventsFunc             func(opts ListOptions) ([]Event, error)
+	CollectionDocumentsFunc    func(collectionID string) ([]NavigationNode, error)
+	DeleteDocumentFunc         func(docID string) error
+	MoveDocumentFunc           func(docID string, collectionID string, parentDocumentID string) error
+	RenameDocumentFunc         func(docID string, title string) error
+	GetSharedDocumentFunc      func(shareID string, docID string) (*Document, error)
+	ExportCollectionFunc       func(collectionID string, format string) (*FileOperation, error)
+	ImportCollectionFunc       func(attachmentID string, format string) (*FileOperation, error)
+	GetFileOperationFunc       func(id string) (*FileOperation, error)
+	DownloadFileOperationFunc  func(id string, w io.Writer) error
+	CreateImportAttachmentFunc func(name string, data []byte) (*Attachment, error)
+	CallFunc                   func(method string, payload any, out any) error
+}
+
+var _ Client = (*MockClient)(nil)
+
+func (m *MockClient) GetDocument(docID string) (*Document, error) {
+	return m.GetDocumentFunc(docID)
+}
+
+func (m *MockClient) UpdateDocument(docID string, content string) error {
+	return m.UpdateDocumentFunc(docID, content)
+}
+
+func (m *MockClient) ListDocuments() ([]Document, error) {
+	return m.ListDocumentsFunc()
+}
+
+func (m *MockClient) CreateDocument(title string, text string, collectionId string, parentDocumentID string) (*Document, error) {
+	return m.CreateDocumentFunc(title, text, collectionId, parentDocumentID)
+}
+
+func (m *MockClient) ListCollections() ([]Collection, error) {
+	return m.ListCollectionsFunc()
+}
+
+func (m *MockClient) SearchDocuments(query string) ([]SearchResult, error) {
+	return m.SearchDocumentsFunc(query)
+}
+
+func (m *MockClient) SearchTitles(query string) ([]Document, error) {
+	return m.SearchTitlesFunc(query)
+}
+
+func (m *MockClient) CreateAttachment(name string, contentType string, data []byte, documentID string) (*Attachment, error) {
+	return m.CreateAttachmentFunc(name, contentType, data, documentID)
+}
+
+func (m *MockClient) DownloadAttachment(attachmentID string) (*AttachmentFile, error) {
+	return m.DownloadAttachmentFunc(attachmentID)
+}
+
+func (m *MockClient) DeleteAttachment(attachmentID string) error {
+	return m.DeleteAttachmentFunc(attachmentID)
+}
+
+func (m *MockClient) ResolveAttachmentURL(attachmentID string) (string, error) {
+	return m.ResolveAttachmentURLFunc(attachmentID)
+}
+
+func (m *MockClient) QueryDocuments(opts ListOptions) ([]Document, error) {
+	return m.QueryDocumentsFunc(opts)
+}
+
+func (m *MockClient) ListEvents(opts ListOptions) ([]Event, error) {
+	return m.ListEventsFunc(opts)
+}
+
+func (m *MockClient) CollectionDocuments(collectionID string) ([]NavigationNode, error) {
+	return m.CollectionDocumentsFunc(collectionID)
+}
+
+func (m *MockClient) DeleteDocument(docID string) error {
+	return m.DeleteDocumentFunc(docID)
+}
+
+func (m *MockClient) MoveDocument(docID string, collectionID string, parentDocumentID string) error {
+	return m.MoveDocumentFunc(docID, collectionID, parentDocumentID)
+}
+
+func (m *MockClient) RenameDocument(docID string, title string) error {
+	return m.RenameDocumentFunc(docID, title)
+}
+
+func (m *MockClient) GetSharedDocument(shareID string, docID string) (*Document, error) {
+	return m.GetSharedDocumentFunc(shareID, docID)
+}
+
+func (m *MockClient) ExportCollection(collectionID string, format string) (*FileOperation, error) {
+	return m.ExportCollectionFunc(collectionID, format)
+}
+
+func (m *MockClient) ImportCollection(attachmentID string, format string) (*FileOperation, error) {
+	return m.ImportCollectionFunc(attachmentID, format)
+}
+
+func (m *MockClient) GetFileOperation(id string) (*FileOperation, error) {
+	return m.GetFileOperationFunc(id)
 }
 
-func (m *MockClient) GetDocument(docID string, verbose bool) (*Document, error) {
-	return m.GetDocumentFunc(docID, verbose)
+func (m *MockClient) DownloadFileOperation(id string, w io.Writer) error {
+	return m.DownloadFileOperationFunc(id, w)
 }
 
-func (m *MockClient) UpdateDocument(docID string, content string, verbose bool) error {
-	return m.UpdateDocumentFunc(docID, content, verbose)
+func (m *MockClient) CreateImportAttachment(name string, data []byte) (*Attachment, error) {
+	return m.CreateImportAttachmentFunc(name, data)
 }
 
-func (m *MockClient) ListDocuments(verbose bool) ([]Document, error) {
-	return m.ListDocumentsFunc(verbose)
+func (m *MockClient) Call(method string, payload any, out any) error {
+	return m.CallFunc(method, payload, out)
 }
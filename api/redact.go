@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// sensitiveHeaders lists request headers whose values must never be logged
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// sensitiveHeaderWords marks custom headers, such as static headers from the
+// config, whose names suggest they carry a credential
+var sensitiveHeaderWords = []string{"secret", "token", "key", "password"}
+
+func isSensitiveHeader(name string) bool {
+	if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return true
+	}
+	lower := strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of h with credential-bearing values masked
+func RedactHeaders(h http.Header) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, v := range h {
+		if isSensitiveHeader(k) {
+			redacted[k] = []string{RedactSecret(strings.Join(v, ", "))}
+			continue
+		}
+		redacted[k] = append([]string(nil), v...)
+	}
+	return redacted
+}
+
+// RedactSecret masks a credential, keeping an auth scheme such as "Bearer"
+// so logs still show what kind of credential was sent
+func RedactSecret(value string) string {
+	if scheme, _, ok := strings.Cut(value, " "); ok {
+		return scheme + " [REDACTED]"
+	}
+	return "[REDACTED]"
+}
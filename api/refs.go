@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DocumentRef is a parsed reference to a document. ShareID is set for
+// share links; ID may then be empty, meaning the shared document itself.
+type DocumentRef struct {
+	ID      string
+	ShareID string
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// slugPattern matches a url-id, optionally prefixed by the title slug
+// Outline puts in front of it in document URLs
+var slugPattern = regexp.MustCompile(`^(?:[0-9A-Za-z_~-]*-)?([0-9A-Za-z]{10,15})$`)
+
+// IsUUID reports whether s is a document UUID
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// ParseDocumentRef accepts a document UUID, a url-id with or without its
+// title slug, a document URL such as https://host/doc/title-Xk3pQ9aB1c or a
+// share URL such as https://host/s/<shareId>
+func ParseDocumentRef(s string) (DocumentRef, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "://") {
+		return DocumentRef{ID: documentID(s)}, nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return DocumentRef{}, fmt.Errorf("parsing document URL: %w", err)
+	}
+
+	var ref DocumentRef
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		switch segments[i] {
+		case "doc":
+			if ref.ID == "" {
+				ref.ID = documentID(segments[i+1])
+			}
+		case "s", "share":
+			if ref.ShareID == "" {
+				ref.ShareID = segments[i+1]
+			}
+		}
+	}
+	if ref.ID == "" && ref.ShareID == "" {
+		return DocumentRef{}, fmt.Errorf("%s is not a document or share URL", s)
+	}
+	return ref, nil
+}
+
+// documentID strips the title slug from a url-id, leaving UUIDs and
+// anything else unchanged
+func documentID(s string) string {
+	if IsUUID(s) {
+		return s
+	}
+	if m := slugPattern.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return s
+}
+
+// LinkedDocumentID returns the url-id or UUID of the document an internal
+// link such as /doc/title-Xk3pQ9aB1c#heading or https://host/doc/... points
+// to
+func LinkedDocumentID(target string) (string, bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 2 || segments[0] != "doc" || segments[1] == "" {
+		return "", false
+	}
+	return documentID(segments[1]), true
+}
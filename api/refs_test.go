@@ -0,0 +1,54 @@
+package api
+
+import "testing"
+
+func TestParseDocumentRef(t *testing.T) {
+	tests := []struct {
+		in   string
+		want DocumentRef
+	}{
+		{"8f2de8e6-a423-4960-8802-18c0da301989", DocumentRef{ID: "8f2de8e6-a423-4960-8802-18c0da301989"}},
+		{"Xk3pQ9aB1c", DocumentRef{ID: "Xk3pQ9aB1c"}},
+		{"runbook-db-failover-Xk3pQ9aB1c", DocumentRef{ID: "Xk3pQ9aB1c"}},
+		{"https://wiki.example.com/doc/runbook-db-failover-Xk3pQ9aB1c", DocumentRef{ID: "Xk3pQ9aB1c"}},
+		{"https://wiki.example.com/doc/runbook-db-failover-Xk3pQ9aB1c/edit#heading", DocumentRef{ID: "Xk3pQ9aB1c"}},
+		{"https://wiki.example.com/s/5c1d7b0e-9b4b-4b4e-8e0a-3f7c2d1a9b8c", DocumentRef{ShareID: "5c1d7b0e-9b4b-4b4e-8e0a-3f7c2d1a9b8c"}},
+		{"https://wiki.example.com/s/my-share/doc/child-page-Ab12Cd34Ef", DocumentRef{ID: "Ab12Cd34Ef", ShareID: "my-share"}},
+		{"legacy-id", DocumentRef{ID: "legacy-id"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseDocumentRef(tt.in)
+		if err != nil {
+			t.Errorf("ParseDocumentRef(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDocumentRef(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := ParseDocumentRef("https://wiki.example.com/collection/runbooks-AbCdEfGhIj"); err == nil {
+		t.Error("expected collection URL to be rejected")
+	}
+}
+
+func TestLinkedDocumentID(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"/doc/runbook-db-failover-Xk3pQ9aB1c", "Xk3pQ9aB1c", true},
+		{"/doc/runbook-db-failover-Xk3pQ9aB1c#step-2", "Xk3pQ9aB1c", true},
+		{"https://wiki.example.com/doc/runbook-Xk3pQ9aB1c/edit", "Xk3pQ9aB1c", true},
+		{"/collection/runbooks-AbCdEfGhIj", "", false},
+		{"https://example.com/", "", false},
+		{"assets/image.png", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := LinkedDocumentID(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("LinkedDocumentID(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxErrorBody limits how much of an unexpected response body an error
+// quotes
+const maxErrorBody = 512
+
+// Error is a request Outline answered with an error status
+type Error struct {
+	// Method is the RPC method called, or the URL for requests outside the
+	// API
+	Method string
+	Status int
+	// Code is Outline's error identifier, such as authentication_required
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("%s: unexpected status %d: %s", e.Method, e.Status, e.Message)
+	}
+	return fmt.Sprintf("%s: %s (status %d): %s", e.Method, e.Code, e.Status, e.Message)
+}
+
+// NotFound reports whether the requested object does not exist
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// responseError builds an Error from a failed response, using Outline's
+// error body when there is one
+func responseError(method string, resp *http.Response, body []byte) *Error {
+	e := &Error{Method: method, Status: resp.StatusCode}
+
+	var apiError struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &apiError) == nil && apiError.Error != "" {
+		e.Code = apiError.Error
+		e.Message = apiError.Message
+		return e
+	}
+
+	e.Message = strings.TrimSpace(string(body))
+	if len(e.Message) > maxErrorBody {
+		e.Message = e.Message[:maxErrorBody] + "..."
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+	return e
+}
+
+// Call sends payload to an RPC method, such as documents.update, and
+// decodes the whole JSON response into out unless it is nil. In dry-run
+// mode a mutating call is reported instead of sent and out is left as is.
+func (c *client) Call(method string, payload any, out any) error {
+	_, err := c.send(method, payload, out, "")
+	return err
+}
+
+// send is the single path every RPC takes. textDiff describes the change a
+// held-back mutation would make; held reports that dry-run mode kept the
+// call from being sent.
+func (c *client) send(method string, payload any, out any, textDiff string) (held bool, err error) {
+	if payload == nil {
+		payload = struct{}{}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("marshaling payload: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.endpoint(method), bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if c.holdBack(method, req, body, textDiff) {
+		return true, nil
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("%s: reading response body: %w", method, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, responseError(method, resp, respBody)
+	}
+
+	if out == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return false, fmt.Errorf("%s: decoding response: %w", method, err)
+	}
+	return false, nil
+}
+
+// endpoint returns the URL of an RPC method
+func (c *client) endpoint(method string) string {
+	return normalizeURL(c.config.OutlineURL) + "/api/" + method
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"time"
+)
+
+// ListOptions filters and pages documents.list and events.list
+type ListOptions struct {
+	CollectionID string `json:"collectionId,omitempty"`
+	Sort         string `json:"sort,omitempty"`
+	Direction    string `json:"direction,omitempty"`
+	Offset       int    `json:"offset,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
+}
+
+// Event is an entry from the events API describing a change to a document
+// or collection
+type Event struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	DocumentID   string    `json:"documentId"`
+	CollectionID string    `json:"collectionId"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// NavigationNode is an entry in a collection's document tree
+type NavigationNode struct {
+	ID       string           `json:"id"`
+	Title    string           `json:"title"`
+	URL      string           `json:"url"`
+	Children []NavigationNode `json:"children"`
+}
+
+func (c *client) QueryDocuments(opts ListOptions) ([]Document, error) {
+	var response struct {
+		Data []Document `json:"data"`
+	}
+	if err := c.Call("documents.list", opts, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (c *client) ListEvents(opts ListOptions) ([]Event, error) {
+	var response struct {
+		Data []Event `json:"data"`
+	}
+	if err := c.Call("events.list", opts, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (c *client) CollectionDocuments(collectionID string) ([]NavigationNode, error) {
+	payload := struct {
+		ID string `json:"id"`
+	}{
+		ID: collectionID,
+	}
+
+	var response struct {
+		Data []NavigationNode `json:"data"`
+	}
+	if err := c.Call("collections.documents", payload, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+func (c *client) DeleteDocument(docID string) error {
+	payload := struct {
+		ID string `json:"id"`
+	}{
+		ID: docID,
+	}
+	return c.Call("documents.delete", payload, nil)
+}
+
+// MoveDocument moves a document to a collection, under parentDocumentID if
+// it is not empty
+func (c *client) MoveDocument(docID string, collectionID string, parentDocumentID string) error {
+	payload := struct {
+		ID               string `json:"id"`
+		CollectionID     string `json:"collectionId"`
+		ParentDocumentID string `json:"parentDocumentId,omitempty"`
+	}{
+		ID:               docID,
+		CollectionID:     collectionID,
+		ParentDocumentID: parentDocumentID,
+	}
+	return c.Call("documents.move", payload, nil)
+}
+
+func (c *client) RenameDocument(docID string, title string) error {
+	payload := struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	}{
+		ID:    docID,
+		Title: title,
+	}
+	return c.Call("documents.update", payload, nil)
+}
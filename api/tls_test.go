@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/pem"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/config"
+)
+
+func TestTransportConfig(t *testing.T) {
+	var gotHeader string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("X-Team")
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	newClient := func(tc config.TransportConfig, opts ...api.Option) api.Client {
+		cfg := &config.Config{OutlineURL: srv.URL, TransportConfig: tc}
+		return api.DefaultClientFactory(cfg, append(opts, api.WithRetries(0, 0))...)
+	}
+
+	if _, err := newClient(config.TransportConfig{}).ListDocuments(); err == nil {
+		t.Error("expected an untrusted certificate to be rejected")
+	}
+
+	c := newClient(config.TransportConfig{CAFile: caFile, Headers: map[string]string{"X-Team": "docs"}})
+	if _, err := c.ListDocuments(); err != nil {
+		t.Fatalf("expected the CA file to be trusted, got %v", err)
+	}
+	if gotHeader != "docs" {
+		t.Errorf("expected the static header to be sent, got %q", gotHeader)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	c = newClient(config.TransportConfig{InsecureSkipVerify: true}, api.WithLogger(logger))
+	if _, err := c.ListDocuments(); err != nil {
+		t.Fatalf("expected insecure_skip_verify to accept the certificate, got %v", err)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "verification is disabled") {
+		t.Errorf("expected a warning about insecure_skip_verify, got %q", logs.String())
+	}
+}
+
+func TestTransportConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tc   config.TransportConfig
+		want string
+	}{
+		{"bad proxy", config.TransportConfig{Proxy: "::"}, "invalid proxy URL"},
+		{"missing CA", config.TransportConfig{CAFile: "/nonexistent/ca.pem"}, "reading CA file"},
+		{"cert without key", config.TransportConfig{ClientCert: "cert.pem"}, "must be set together"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := api.NewTransport(tt.tc); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+
+			// The client reports the problem on first use
+			cfg := &config.Config{OutlineURL: "https://outline.example", TransportConfig: tt.tc}
+			if _, err := api.DefaultClientFactory(cfg).ListDocuments(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected client error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,395 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"outline-cli/config"
+)
+
+// UserAgent identifies the CLI to Outline
+var UserAgent = "outline-cli"
+
+// Middleware wraps the transport every request of a client goes through
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripFunc adapts a function to http.RoundTripper
+type RoundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware adds middleware outside the built-in chain, so it sees each
+// call once, before caching and retries
+func WithMiddleware(mw ...Middleware) Option {
+	return func(c *client) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// WithRetries sets how many times a failed request is retried and the delay
+// before the first retry, which doubles on each attempt. Zero disables
+// retries.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// WithRateLimit spaces requests at least interval apart
+func WithRateLimit(interval time.Duration) Option {
+	return func(c *client) {
+		c.rateLimit = interval
+	}
+}
+
+// WithCache serves repeated read-only calls from memory for ttl. Any other
+// call clears the cache.
+func WithCache(ttl time.Duration) Option {
+	return func(c *client) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithMetrics records per-method call counts and latency into m
+func WithMetrics(m *Metrics) Option {
+	return func(c *client) {
+		c.metrics = m
+	}
+}
+
+// Defaults for WithRetries
+const (
+	defaultRetries = 3
+	defaultBackoff = 500 * time.Millisecond
+	// maxRetryDelay caps both backoff and a server's Retry-After
+	maxRetryDelay = 30 * time.Second
+)
+
+// rpcMethod returns the RPC method a request calls, such as documents.info,
+// or "" for requests outside the API, such as storage uploads
+func rpcMethod(req *http.Request) string {
+	method, ok := strings.CutPrefix(req.URL.Path, "/api/")
+	if !ok {
+		return ""
+	}
+	return method
+}
+
+// NewTransport builds the transport for a connection config. The zero
+// config shares one pooled transport across clients.
+func NewTransport(tc config.TransportConfig) (http.RoundTripper, error) {
+	if tc.Proxy == "" && tc.CAFile == "" && !tc.InsecureSkipVerify &&
+		tc.ClientCert == "" && tc.ClientKey == "" && tc.ConnectTimeout == 0 && tc.ReadTimeout == 0 {
+		return sharedTransport, nil
+	}
+
+	t := newSharedTransport()
+	if tc.Proxy != "" {
+		proxy, err := url.Parse(tc.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", tc.Proxy)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+
+	if tc.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: time.Duration(tc.ConnectTimeout), KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+		t.TLSHandshakeTimeout = time.Duration(tc.ConnectTimeout)
+	}
+	if tc.ReadTimeout > 0 {
+		t.ResponseHeaderTimeout = time.Duration(tc.ReadTimeout)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: tc.InsecureSkipVerify}
+	if tc.CAFile != "" {
+		pem, err := os.ReadFile(tc.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", tc.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tc.ClientCert != "" || tc.ClientKey != "" {
+		if tc.ClientCert == "" || tc.ClientKey == "" {
+			return nil, fmt.Errorf("client_cert and client_key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(tc.ClientCert, tc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	t.TLSClientConfig = tlsConfig
+	return t, nil
+}
+
+// transport assembles the middleware chain around the base transport.
+// Listed outermost first, each request passes through user middleware,
+// metrics, cache, retry, rate limiting, static headers, user-agent, auth
+// and logging.
+func (c *client) transport() http.RoundTripper {
+	chain := []Middleware{}
+	chain = append(chain, c.middleware...)
+	if c.metrics != nil {
+		chain = append(chain, c.metrics.middleware)
+	}
+	if c.cacheTTL > 0 {
+		chain = append(chain, newCache(c.cacheTTL).middleware)
+	}
+	if c.retries > 0 {
+		chain = append(chain, retry(c.retries, c.backoff, c.logger))
+	}
+	if c.rateLimit > 0 {
+		chain = append(chain, rateLimit(c.rateLimit))
+	}
+	chain = append(chain, c.headers, userAgent, c.auth, c.logging)
+
+	rt := c.base
+	for i := len(chain) - 1; i >= 0; i-- {
+		rt = chain[i](rt)
+	}
+	return rt
+}
+
+// headers adds the configured static headers to requests for the Outline
+// host
+func (c *client) headers(next http.RoundTripper) http.RoundTripper {
+	if len(c.config.Headers) == 0 {
+		return next
+	}
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !c.isOutlineHost(req.URL) {
+			return next.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		for k, v := range c.config.Headers {
+			req.Header.Set(k, v)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+func userAgent(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Header.Get("User-Agent") == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("User-Agent", UserAgent)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// auth adds the API key to requests for the Outline host only, so
+// presigned storage URLs and redirects elsewhere never receive it
+func (c *client) auth(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if c.isOutlineHost(req.URL) && req.Header.Get("Authorization") == "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+func (c *client) isOutlineHost(u *url.URL) bool {
+	base, err := url.Parse(c.config.OutlineURL)
+	return err == nil && base.Host == u.Host
+}
+
+// logging records each attempt at debug level with secrets redacted
+func (c *client) logging(next http.RoundTripper) http.RoundTripper {
+	return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if !c.logger.Enabled(req.Context(), slog.LevelDebug) {
+			return next.RoundTrip(req)
+		}
+
+		var body []byte
+		if req.GetBody != nil {
+			if r, err := req.GetBody(); err == nil {
+				body, _ = io.ReadAll(r)
+				r.Close()
+			}
+		}
+		c.logger.Debug("api request",
+			"method", req.Method,
+			"url", req.URL.String(),
+			"headers", RedactHeaders(req.Header),
+			"body", string(body),
+		)
+
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			c.logger.Debug("api request failed", "url", req.URL.String(), "error", err)
+			return nil, err
+		}
+
+		attrs := []any{
+			"url", req.URL.String(),
+			"status", resp.Status,
+			"duration", time.Since(start),
+		}
+		if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+			data, err := drain(&resp.Body)
+			if err != nil {
+				return nil, err
+			}
+			attrs = append(attrs, "body", string(data))
+		}
+		c.logger.Debug("api response", attrs...)
+		return resp, nil
+	})
+}
+
+// retry resends requests that hit a rate limit, and read-only requests that
+// failed on the network or with a gateway error, backing off between
+// attempts and honoring Retry-After
+func retry(retries int, backoff time.Duration, logger *slog.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			safe := req.Method == http.MethodGet || req.Method == http.MethodHead || IsReadOnly(rpcMethod(req))
+			delay := backoff
+
+			for attempt := 0; ; attempt++ {
+				resp, err := next.RoundTrip(req)
+				if attempt == retries || !retryable(resp, err, safe) {
+					return resp, err
+				}
+				if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+					return resp, err
+				}
+
+				wait := jitter(delay)
+				if resp != nil {
+					if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+						wait = after
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				wait = min(wait, maxRetryDelay)
+				logger.Info("retrying request", "url", req.URL.String(), "attempt", attempt+1, "wait", wait, "reason", retryReason(resp, err))
+
+				select {
+				case <-time.After(wait):
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+				delay = min(delay*2, maxRetryDelay)
+
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, fmt.Errorf("rewinding request body: %w", err)
+					}
+					req = req.Clone(req.Context())
+					req.Body = body
+				}
+			}
+		})
+	}
+}
+
+func retryable(resp *http.Response, err error, safe bool) bool {
+	if err != nil {
+		return safe && transient(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return safe
+	}
+	return false
+}
+
+// transient reports whether a transport error is a network failure worth
+// retrying, rather than a cancellation or a local problem
+func transient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
+
+// jitter spreads retries from concurrent workers by up to a quarter of d
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d + rand.N(d/4+1)
+}
+
+// rateLimit spaces requests at least interval apart, across every
+// goroutine sharing the client
+func rateLimit(interval time.Duration) Middleware {
+	var mu sync.Mutex
+	var next time.Time
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			now := time.Now()
+			slot := now
+			if next.After(now) {
+				slot = next
+			}
+			next = slot.Add(interval)
+			mu.Unlock()
+
+			if wait := slot.Sub(now); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-req.Context().Done():
+					return nil, req.Context().Err()
+				}
+			}
+			return rt.RoundTrip(req)
+		})
+	}
+}
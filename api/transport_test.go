@@ -0,0 +1,170 @@
+package api_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/config"
+	"outline-cli/outlinetest"
+)
+
+func TestRetriesReadsButNotMutations(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	doc := srv.AddDocument(api.Document{Title: "Doc", Text: "Original"})
+	c := api.DefaultClientFactory(srv.Config(), api.WithRetries(2, 0))
+
+	srv.Fail("documents.info", outlinetest.Fault{Status: http.StatusServiceUnavailable, Times: 2})
+	if _, err := c.GetDocument(doc.ID); err != nil {
+		t.Fatalf("expected the read to succeed on retry, got %v", err)
+	}
+
+	srv.Fail("documents.update", outlinetest.Fault{Status: http.StatusBadGateway, Times: 1})
+	if err := c.UpdateDocument(doc.ID, "Changed"); err == nil {
+		t.Error("expected a failed update not to be retried")
+	}
+
+	// A rate limit means the request wasn't processed, so even mutations
+	// are resent, with their body intact
+	srv.Fail("documents.update", outlinetest.Fault{Status: http.StatusTooManyRequests, Times: 1})
+	if err := c.UpdateDocument(doc.ID, "Changed"); err != nil {
+		t.Fatalf("expected the rate-limited update to be retried, got %v", err)
+	}
+	if got, _ := srv.Document(doc.ID); got.Text != "Changed" {
+		t.Errorf("expected retried update to apply, got %q", got.Text)
+	}
+}
+
+func TestErrorsCarryOutlineCode(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	c := api.DefaultClientFactory(srv.Config(), api.WithRetries(0, 0))
+	_, err := c.GetDocument("00000000-0000-4000-8000-999999999999")
+
+	var apiErr *api.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *api.Error, got %v", err)
+	}
+	if !apiErr.NotFound() || apiErr.Method != "documents.info" || apiErr.Code != "not_found" {
+		t.Errorf("unexpected error fields: %+v", apiErr)
+	}
+}
+
+func TestCacheServesReadsUntilAMutation(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	doc := srv.AddDocument(api.Document{Title: "Doc", Text: "Original"})
+	c := api.DefaultClientFactory(srv.Config(), api.WithCache(time.Minute))
+
+	countInfo := func() int {
+		n := 0
+		for _, call := range srv.Calls() {
+			if call == "documents.info" {
+				n++
+			}
+		}
+		return n
+	}
+
+	for range 3 {
+		if _, err := c.GetDocument(doc.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countInfo(); n != 1 {
+		t.Errorf("expected repeated reads to hit the cache, got %d calls", n)
+	}
+
+	if err := c.UpdateDocument(doc.ID, "Changed"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetDocument(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "Changed" || countInfo() != 2 {
+		t.Errorf("expected the update to invalidate the cache, got %q after %d calls", got.Text, countInfo())
+	}
+}
+
+func TestCredentialsOnlyGoToOutline(t *testing.T) {
+	var storageAuth, storageAgent string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storageAuth = r.Header.Get("Authorization")
+		storageAgent = r.Header.Get("User-Agent")
+		w.Write([]byte("file contents"))
+	}))
+	defer storage.Close()
+
+	var outlineAuth string
+	outline := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outlineAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, storage.URL+"/bucket/file.txt?signature=abc", http.StatusFound)
+	}))
+	defer outline.Close()
+
+	c := api.DefaultClientFactory(&config.Config{APIKey: "secret-key", OutlineURL: outline.URL})
+	file, err := c.DownloadAttachment("att-1")
+	if err != nil {
+		t.Fatalf("DownloadAttachment: %v", err)
+	}
+	if string(file.Data) != "file contents" {
+		t.Errorf("unexpected data %q", file.Data)
+	}
+	if outlineAuth != "Bearer secret-key" {
+		t.Errorf("expected Outline to receive the API key, got %q", outlineAuth)
+	}
+	if storageAuth != "" {
+		t.Errorf("storage received credentials: %q", storageAuth)
+	}
+	if !strings.HasPrefix(storageAgent, api.UserAgent) {
+		t.Errorf("expected user agent %q, got %q", api.UserAgent, storageAgent)
+	}
+}
+
+func TestMetricsAndMiddleware(t *testing.T) {
+	srv := outlinetest.NewServer()
+	defer srv.Close()
+
+	var seen []string
+	trace := func(next http.RoundTripper) http.RoundTripper {
+		return api.RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			seen = append(seen, req.URL.Path)
+			return next.RoundTrip(req)
+		})
+	}
+
+	metrics := api.NewMetrics()
+	c := api.DefaultClientFactory(srv.Config(), api.WithMetrics(metrics), api.WithMiddleware(trace), api.WithRetries(0, 0))
+
+	srv.Fail("collections.list", outlinetest.Fault{Status: http.StatusInternalServerError, Times: 1})
+	c.ListCollections()
+	if _, err := c.ListCollections(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListDocuments(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := metrics.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for 2 methods, got %+v", stats)
+	}
+	if s := stats[0]; s.Method != "collections.list" || s.Calls != 2 || s.Errors != 1 {
+		t.Errorf("unexpected collections.list stats: %+v", s)
+	}
+	if s := stats[1]; s.Method != "documents.list" || s.Calls != 1 || s.Errors != 0 {
+		t.Errorf("unexpected documents.list stats: %+v", s)
+	}
+	if len(seen) != 3 {
+		t.Errorf("expected custom middleware to see 3 requests, got %v", seen)
+	}
+}
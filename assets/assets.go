@@ -0,0 +1,178 @@
+// Package assets rewrites attachment references in document Markdown so
+// documents can be edited offline with their images alongside them.
+package assets
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"outline-cli/api"
+)
+
+// Dir is the directory, relative to a document, that holds its attachments
+const Dir = "assets"
+
+// attachmentID matches the UUID of an attachment. Both directions of the
+// rewrite use it, so only links that can be reversed are localized and
+// other files a user keeps in Dir are left alone.
+const attachmentID = `([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`
+
+// remoteRef matches attachment URLs, absolute or relative to the Outline host
+var remoteRef = regexp.MustCompile(`(?:https?://[^\s()"'<>]+)?/api/attachments\.redirect\?id=` + attachmentID)
+
+// localRef matches relative links to downloaded attachments
+var localRef = regexp.MustCompile(`(?:\./)?` + Dir + `/` + attachmentID + `(?:\.[0-9A-Za-z]+)?`)
+
+// linkTarget matches the target of Markdown links and images
+var linkTarget = regexp.MustCompile(`(!?)\[[^\]]*\]\(<?([^)\s>]+)>?`)
+
+// safeExt matches extensions that can't smuggle path separators or other
+// surprises from an uploaded file name into a local one
+var safeExt = regexp.MustCompile(`^\.[0-9a-z]{1,10}$`)
+
+// RemoteIDs returns the distinct attachment IDs referenced by text, in
+// order of first appearance
+func RemoteIDs(text string) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, m := range remoteRef.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
+
+// FileName returns the name an attachment is stored under in Dir. The ID
+// is kept in the name so the link can be reversed on push.
+func FileName(attachmentID string, originalName string, contentType string) string {
+	ext := path.Ext(originalName)
+	if ext == "" {
+		if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	ext = strings.ToLower(ext)
+	if !safeExt.MatchString(ext) {
+		ext = ""
+	}
+	return attachmentID + ext
+}
+
+// Localize replaces attachment URLs with relative paths. paths maps
+// attachment IDs to file names within Dir; unknown IDs are left untouched.
+func Localize(text string, paths map[string]string) string {
+	return remoteRef.ReplaceAllStringFunc(text, func(ref string) string {
+		id := remoteRef.FindStringSubmatch(ref)[1]
+		if name, ok := paths[id]; ok {
+			return Dir + "/" + name
+		}
+		return ref
+	})
+}
+
+// Relink points attachment URLs at other attachments, such as copies on
+// another Outline instance. ids maps old attachment IDs to new ones;
+// unknown IDs are left untouched.
+func Relink(text string, ids map[string]string) string {
+	return remoteRef.ReplaceAllStringFunc(text, func(ref string) string {
+		if id, ok := ids[remoteRef.FindStringSubmatch(ref)[1]]; ok {
+			return api.AttachmentURL(id)
+		}
+		return ref
+	})
+}
+
+// Unlocalize reverses Localize, turning relative asset paths back into
+// attachment URLs
+func Unlocalize(text string) string {
+	return localRef.ReplaceAllStringFunc(text, func(ref string) string {
+		return api.AttachmentURL(localRef.FindStringSubmatch(ref)[1])
+	})
+}
+
+// LocalFiles returns the distinct link targets in text that refer to local
+// files other than Markdown documents, such as newly added images
+func LocalFiles(text string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, m := range linkTarget.FindAllStringSubmatch(text, -1) {
+		target := m[2]
+		if seen[target] || !isLocalFile(target) {
+			continue
+		}
+		seen[target] = true
+		files = append(files, target)
+	}
+	return files
+}
+
+func isLocalFile(target string) bool {
+	if strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") || localRef.MatchString(target) {
+		return false
+	}
+	if u, err := url.Parse(target); err != nil || u.Scheme != "" || u.Host != "" {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(target))
+	return ext != "" && ext != ".md" && ext != ".markdown"
+}
+
+// Links returns the distinct targets of the Markdown links and images in
+// text, in order of first appearance
+func Links(text string) []string {
+	seen := make(map[string]bool)
+	var links []string
+	for _, m := range linkTarget.FindAllStringSubmatch(text, -1) {
+		if !seen[m[2]] {
+			seen[m[2]] = true
+			links = append(links, m[2])
+		}
+	}
+	return links
+}
+
+// DocumentLinks returns the distinct relative link targets in text that
+// refer to Markdown documents or to folders, such as ../setup.md#install
+// or guides/
+func DocumentLinks(text string) []string {
+	seen := make(map[string]bool)
+	var links []string
+	for _, m := range linkTarget.FindAllStringSubmatch(text, -1) {
+		target := m[2]
+		if m[1] != "" || seen[target] || !isDocumentLink(target) {
+			continue
+		}
+		seen[target] = true
+		links = append(links, target)
+	}
+	return links
+}
+
+func isDocumentLink(target string) bool {
+	if strings.HasPrefix(target, "/") || strings.HasPrefix(target, "#") {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	return ext == ".md" || ext == ".markdown" || strings.HasSuffix(u.Path, "/")
+}
+
+// ReplaceLink replaces every link target equal to old with replacement
+func ReplaceLink(text string, old string, replacement string) string {
+	return linkTarget.ReplaceAllStringFunc(text, func(link string) string {
+		m := linkTarget.FindStringSubmatchIndex(link)
+		if link[m[4]:m[5]] != old {
+			return link
+		}
+		return link[:m[4]] + replacement + link[m[5]:]
+	})
+}
@@ -0,0 +1,86 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testID = "0f1e2d3c-4b5a-6978-8a9b-0c1d2e3f4a5b"
+
+func TestLocalizeRoundTrip(t *testing.T) {
+	text := `![chart](https://wiki.example.com/api/attachments.redirect?id=` + testID + ` "Chart")` + "\n" +
+		`[spec](/api/attachments.redirect?id=` + testID + `)`
+
+	if ids := RemoteIDs(text); !reflect.DeepEqual(ids, []string{testID}) {
+		t.Errorf("expected ids %v, got %v", []string{testID}, ids)
+	}
+
+	local := Localize(text, map[string]string{testID: testID + ".png"})
+	want := `![chart](assets/` + testID + `.png "Chart")` + "\n" + `[spec](assets/` + testID + `.png)`
+	if local != want {
+		t.Errorf("expected %q, got %q", want, local)
+	}
+
+	back := Unlocalize(local)
+	want = `![chart](/api/attachments.redirect?id=` + testID + ` "Chart")` + "\n" + `[spec](/api/attachments.redirect?id=` + testID + `)`
+	if back != want {
+		t.Errorf("expected %q, got %q", want, back)
+	}
+}
+
+func TestLocalFiles(t *testing.T) {
+	text := "![a](img/a.png) ![b](https://example.com/b.png) [doc](other.md) " +
+		"[c](assets/" + testID + ".pdf) [d](#anchor) ![a again](img/a.png) [e](files/e.pdf)"
+
+	want := []string{"img/a.png", "files/e.pdf"}
+	if got := LocalFiles(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRelink(t *testing.T) {
+	const (
+		oldA = "1a000000-0000-4000-8000-000000000001"
+		oldB = "1b000000-0000-4000-8000-000000000002"
+		newA = "2a000000-0000-4000-8000-000000000003"
+	)
+	text := "![a](https://old.example.com/api/attachments.redirect?id=" + oldA + ") [b](/api/attachments.redirect?id=" + oldB + ")"
+	got := Relink(text, map[string]string{oldA: newA})
+	want := "![a](/api/attachments.redirect?id=" + newA + ") [b](/api/attachments.redirect?id=" + oldB + ")"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestOnlyReversibleLinksAreRewritten(t *testing.T) {
+	// An ID that wouldn't be recognised once localized stays remote
+	text := "![x](/api/attachments.redirect?id=not-a-uuid) ![logo](assets/logo.png)"
+	if ids := RemoteIDs(text); len(ids) != 0 {
+		t.Errorf("expected no attachment IDs, got %v", ids)
+	}
+	if got := Unlocalize(text); got != text {
+		t.Errorf("expected a file added to %s left alone, got %q", Dir, got)
+	}
+	if got := LocalFiles(text); !reflect.DeepEqual(got, []string{"assets/logo.png"}) {
+		t.Errorf("expected the added file to be uploaded, got %v", got)
+	}
+}
+
+func TestDocumentLinks(t *testing.T) {
+	text := "[setup](../setup.md#install) [guides](guides/) ![img](img/a.png) [site](https://example.com/a.md) " +
+		"[abs](/doc/a-Xk3pQ9aB1c) [again](../setup.md#install) [readme](README.markdown)"
+
+	want := []string{"../setup.md#install", "guides/", "README.markdown"}
+	if got := DocumentLinks(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestReplaceLink(t *testing.T) {
+	text := "![a](a.png) ![b](b.png) ![a2](a.png \"title\")"
+	got := ReplaceLink(text, "a.png", "/api/attachments.redirect?id=x")
+	want := "![a](/api/attachments.redirect?id=x) ![b](b.png) ![a2](/api/attachments.redirect?id=x \"title\")"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"archive/tar"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Identification of full workspace archives
+const (
+	ArchiveFormat  = "outline-cli-backup"
+	ArchiveVersion = 1
+)
+
+// ManifestFile is the first entry of every archive
+const ManifestFile = "manifest.json"
+
+// archiveReadme is written into every archive so it can be understood
+// without the CLI
+const archiveReadme = `This is a backup of an Outline workspace made by outline-cli.
+
+It is a tar archive compressed with zstd. Entries:
+
+  manifest.json            format, version, source instance and everything
+                           that existed when the backup was made
+  collections/<id>.json    a collection as returned by collections.list, with
+                           its document tree under "documentStructure"
+  documents/<id>.json      a document's metadata, status (published, draft or
+                           archived) and revision metadata
+  documents/<id>.md        a document's Markdown text
+  attachments/<id>.json    an attachment's name, content type and size
+  attachments/<id>.data    an attachment's content
+
+An incremental backup names the backup it builds on in "base" and only holds
+documents and attachments that changed since; the manifest still lists
+everything, so the chain of archives up to it describes the whole workspace.
+`
+
+// Manifest describes an archive and the workspace at the time it was made
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Source is the URL of the Outline instance backed up
+	Source string `json:"source"`
+	// Base is the ID of the backup this one is incremental against
+	Base string `json:"base,omitempty"`
+	// IncludesArchived is set when archived documents were backed up
+	IncludesArchived bool `json:"includesArchived"`
+	// Collections, Documents and Attachments list everything that existed,
+	// whether its content is in this archive or in a base
+	Collections []string                 `json:"collections"`
+	Documents   map[string]DocumentEntry `json:"documents"`
+	Attachments []string                 `json:"attachments"`
+}
+
+// DocumentEntry is a document listed in a manifest
+type DocumentEntry struct {
+	// Status is published, draft or archived
+	Status    string    `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Attachments are the IDs of the attachments the text refers to
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// NewManifest returns a manifest for a new backup of source
+func NewManifest(source string, now time.Time) *Manifest {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return &Manifest{
+		Format:    ArchiveFormat,
+		Version:   ArchiveVersion,
+		ID:        now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix),
+		CreatedAt: now.UTC(),
+		Source:    source,
+		Documents: make(map[string]DocumentEntry),
+	}
+}
+
+// ArchiveWriter writes the entries of an archive
+type ArchiveWriter struct {
+	zw *zstd.Encoder
+	tw *tar.Writer
+}
+
+// NewArchiveWriter starts an archive on w with its manifest
+func NewArchiveWriter(w io.Writer, m *Manifest) (*ArchiveWriter, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	aw := &ArchiveWriter{zw: zw, tw: tar.NewWriter(zw)}
+	if err := aw.WriteJSON(ManifestFile, m); err != nil {
+		return nil, err
+	}
+	if err := aw.WriteFile("README.txt", []byte(archiveReadme)); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// WriteFile adds an entry holding data
+func (aw *ArchiveWriter) WriteFile(name string, data []byte) error {
+	hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := aw.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := aw.tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// WriteJSON adds an entry holding v as indented JSON
+func (aw *ArchiveWriter) WriteJSON(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", name, err)
+	}
+	return aw.WriteFile(name, data)
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (aw *ArchiveWriter) Close() error {
+	if err := aw.tw.Close(); err != nil {
+		return err
+	}
+	return aw.zw.Close()
+}
+
+// ArchiveReader reads the entries of an archive in order
+type ArchiveReader struct {
+	f        *os.File
+	zr       *zstd.Decoder
+	tr       *tar.Reader
+	Manifest *Manifest
+}
+
+// OpenArchive opens an archive and reads its manifest
+func OpenArchive(filename string) (*ArchiveReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	ar := &ArchiveReader{f: f, zr: zr, tr: tar.NewReader(zr)}
+
+	name, r, err := ar.Next()
+	if err == nil && name != ManifestFile {
+		err = fmt.Errorf("first entry is %s, not %s", name, ManifestFile)
+	}
+	if err == nil {
+		ar.Manifest = &Manifest{}
+		err = json.NewDecoder(r).Decode(ar.Manifest)
+	}
+	if err == nil && ar.Manifest.Format != ArchiveFormat {
+		err = fmt.Errorf("not an %s archive", ArchiveFormat)
+	}
+	if err == nil && ar.Manifest.Version > ArchiveVersion {
+		err = fmt.Errorf("archive version %d is newer than the supported version %d", ar.Manifest.Version, ArchiveVersion)
+	}
+	if err != nil {
+		ar.Close()
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	return ar, nil
+}
+
+// Next returns the next entry, or io.EOF at the end of the archive
+func (ar *ArchiveReader) Next() (string, io.Reader, error) {
+	hdr, err := ar.tr.Next()
+	if err != nil {
+		return "", nil, err
+	}
+	return hdr.Name, ar.tr, nil
+}
+
+func (ar *ArchiveReader) Close() error {
+	ar.zr.Close()
+	return ar.f.Close()
+}
@@ -0,0 +1,218 @@
+// Package backup keeps local snapshots of remote document content so an
+// overwrite can be undone without Outline's revision history, and reads
+// and writes archives of a whole workspace for disaster recovery.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/workspace"
+)
+
+// nameLayout formats snapshot names so they sort chronologically
+const nameLayout = "20060102T150405.000000000Z"
+
+// DefaultKeep is the number of snapshots kept per document when not configured
+const DefaultKeep = 20
+
+// Snapshot is a saved copy of a document's remote content
+type Snapshot struct {
+	DocumentID string    `json:"documentId"`
+	Name       string    `json:"snapshot"`
+	Title      string    `json:"title"`
+	Version    int       `json:"version"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	SavedAt    time.Time `json:"savedAt"`
+	Size       int       `json:"size"`
+	Text       string    `json:"text,omitempty"`
+}
+
+// Store saves snapshots below a directory, one subdirectory per document
+type Store struct {
+	dir    string
+	keep   int
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// NewStore returns a store in dir that keeps at most keep snapshots per
+// document, none older than maxAge when it is non-zero
+func NewStore(dir string, keep int, maxAge time.Duration) *Store {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Store{dir: dir, keep: keep, maxAge: maxAge, now: time.Now}
+}
+
+// Save snapshots doc unless its text matches the latest snapshot, then
+// applies the retention policy
+func (s *Store) Save(doc *api.Document) (*Snapshot, error) {
+	if err := workspace.ValidateID(doc.ID); err != nil {
+		return nil, err
+	}
+
+	if latest, err := s.Latest(doc.ID); err == nil && latest.Text == doc.Text {
+		return latest, nil
+	}
+
+	now := s.now().UTC()
+	snap := &Snapshot{
+		DocumentID: doc.ID,
+		Name:       now.Format(nameLayout),
+		Title:      doc.Title,
+		Version:    doc.Version,
+		UpdatedAt:  doc.UpdatedAt,
+		SavedAt:    now,
+		Size:       len(doc.Text),
+		Text:       doc.Text,
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	docDir := filepath.Join(s.dir, doc.ID)
+	if err := os.MkdirAll(docDir, 0700); err != nil {
+		return nil, fmt.Errorf("creating backup directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(docDir, snap.Name+".json"), data, 0600); err != nil {
+		return nil, fmt.Errorf("writing snapshot: %w", err)
+	}
+
+	if err := s.prune(doc.ID); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// List returns the snapshots of a document, or of every document when
+// docID is empty, oldest first. Text is omitted.
+func (s *Store) List(docID string) ([]Snapshot, error) {
+	ids := []string{docID}
+	if docID == "" {
+		entries, err := os.ReadDir(s.dir)
+		if errors.Is(err, os.ErrNotExist) {
+			return []Snapshot{}, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading backup directory: %w", err)
+		}
+		ids = ids[:0]
+		for _, e := range entries {
+			if e.IsDir() && workspace.ValidateID(e.Name()) == nil {
+				ids = append(ids, e.Name())
+			}
+		}
+	}
+
+	snapshots := []Snapshot{}
+	for _, id := range ids {
+		names, err := s.names(id)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			snap, err := s.Load(id, name)
+			if err != nil {
+				return nil, err
+			}
+			snap.Text = ""
+			snapshots = append(snapshots, *snap)
+		}
+	}
+	return snapshots, nil
+}
+
+// Load reads a snapshot by document ID and name
+func (s *Store) Load(docID string, name string) (*Snapshot, error) {
+	if err := workspace.ValidateID(docID); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse(nameLayout, name); err != nil {
+		return nil, fmt.Errorf("invalid snapshot name %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, docID, name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no snapshot %s for document %s", name, docID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// Latest returns the most recent snapshot of a document
+func (s *Store) Latest(docID string) (*Snapshot, error) {
+	names, err := s.names(docID)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no snapshots for document %s", docID)
+	}
+	return s.Load(docID, names[len(names)-1])
+}
+
+// names lists a document's snapshot names, oldest first
+func (s *Store) names(docID string) ([]string, error) {
+	if err := workspace.ValidateID(docID); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, docID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading backups for %s: %w", docID, err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// prune removes snapshots beyond the retention limits, always keeping the
+// newest one
+func (s *Store) prune(docID string) error {
+	names, err := s.names(docID)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range names[:len(names)-1] {
+		expired := len(names)-i > s.keep
+		if !expired && s.maxAge > 0 {
+			if t, err := time.Parse(nameLayout, name); err == nil && s.now().Sub(t) > s.maxAge {
+				expired = true
+			}
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, docID, name+".json")); err != nil {
+			return fmt.Errorf("pruning snapshot: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package backup
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"outline-cli/api"
+)
+
+func TestSaveSkipsUnchangedText(t *testing.T) {
+	store := NewStore(t.TempDir(), 0, 0)
+
+	doc := &api.Document{ID: "doc-1", Title: "Doc", Text: "first"}
+	if _, err := store.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshots, err := store.List("doc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %d", len(snapshots))
+	}
+
+	latest, err := store.Latest("doc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Text != "first" || latest.Title != "Doc" {
+		t.Errorf("unexpected snapshot %+v", latest)
+	}
+}
+
+func TestSavePrunesOldSnapshots(t *testing.T) {
+	store := NewStore(t.TempDir(), 2, 48*time.Hour)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	for _, text := range []string{"a", "b", "c"} {
+		if _, err := store.Save(&api.Document{ID: "doc-1", Text: text}); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	snapshots, err := store.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots after pruning by count, got %d", len(snapshots))
+	}
+
+	// Everything but the newest expires by age
+	now = now.Add(72 * time.Hour)
+	if _, err := store.Save(&api.Document{ID: "doc-1", Text: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err = store.List("doc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot after pruning by age, got %d", len(snapshots))
+	}
+
+	latest, err := store.Latest("doc-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Text != "d" {
+		t.Errorf("expected newest snapshot to survive, got %q", latest.Text)
+	}
+}
+
+func TestSaveRejectsUnsafeIDs(t *testing.T) {
+	store := NewStore(t.TempDir(), 0, 0)
+	for _, id := range []string{"", "..", "../escape", `a\b`} {
+		if _, err := store.Save(&api.Document{ID: id, Text: "x"}); err == nil {
+			t.Errorf("expected %q to be rejected", id)
+		}
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "backup.tar.zst")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewManifest("https://wiki.example.com", time.Now())
+	m.Documents["doc-1"] = DocumentEntry{Attachments: []string{"att-1"}}
+	aw, err := NewArchiveWriter(f, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.WriteFile("documents/doc-1.md", []byte("text")); err != nil {
+		t.Fatal(err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	ar, err := OpenArchive(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ar.Close()
+	if ar.Manifest.ID != m.ID || ar.Manifest.Documents["doc-1"].Attachments[0] != "att-1" {
+		t.Errorf("unexpected manifest %+v", ar.Manifest)
+	}
+
+	var names []string
+	for {
+		name, r, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		if name == "documents/doc-1.md" && string(data) != "text" {
+			t.Errorf("unexpected content %q", data)
+		}
+		names = append(names, name)
+	}
+	if strings.Join(names, " ") != "README.txt documents/doc-1.md" {
+		t.Errorf("unexpected entries %v", names)
+	}
+}
+
+func TestOpenArchiveRejectsOtherFiles(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "notes.tar.zst")
+	if err := os.WriteFile(filename, []byte("not an archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenArchive(filename); err == nil {
+		t.Fatal("expected an error")
+	}
+}
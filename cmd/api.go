@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"outline-cli/config"
+
+	"github.com/itchyny/gojq"
+	"github.com/spf13/cobra"
+)
+
+var apiData string
+var apiFields []string
+var apiRawFields []string
+var apiPaginate bool
+var apiJQ string
+
+// rpcMethodPattern matches Outline RPC method names such as
+// documents.search_titles
+var rpcMethodPattern = regexp.MustCompile(`^[A-Za-z]+\.[A-Za-z_]+$`)
+
+// apiPageSize is the page size --paginate asks for, Outline's maximum
+const apiPageSize = 100
+
+var apiCmd = &cobra.Command{
+	Use:   "api <method>",
+	Short: "Call any Outline API method",
+	Long: `Call any Outline API method and print the JSON response.
+
+The method, such as shares.list or documents.archive, is POSTed to
+/api/<method> with the configured credentials, retries and dry-run
+handling. The request body is built from --data and the fields:
+
+  -F key=value   typed field: true, false, null and numbers are sent as JSON
+                 values, @file as the file's contents, anything else as a string
+  -f key=value   string field, sent as is
+
+--paginate follows list pagination and prints every page's data as one
+array. --jq filters the response with a jq expression; string results are
+printed without quotes.
+
+Methods not known to be read-only are treated as changes: --dry-run reports
+them instead of sending them.`,
+	Example: `  outline api auth.info
+  outline api documents.list -F limit=5 --jq '.data[].title'
+  outline api shares.list --paginate --jq '.data[] | .url'
+  outline api documents.archive -f id=abc123
+  echo '{"query": "runbook"}' | outline api documents.search --data -`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		method := strings.TrimPrefix(args[0], "/api/")
+		if !rpcMethodPattern.MatchString(method) {
+			return fmt.Errorf("invalid method %q: expected a name such as documents.info", args[0])
+		}
+
+		var query *gojq.Query
+		if apiJQ != "" {
+			q, err := gojq.Parse(apiJQ)
+			if err != nil {
+				return fmt.Errorf("parsing --jq: %w", err)
+			}
+			query = q
+		}
+
+		payload, err := apiPayload(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		client := newClient(cfg)
+
+		var response map[string]any
+		if apiPaginate {
+			response, err = callPaginated(client.Call, method, payload)
+		} else {
+			err = client.Call(method, payload, &response)
+		}
+		if err != nil {
+			return err
+		}
+		if response == nil {
+			// Held back by --dry-run
+			return nil
+		}
+
+		if query == nil {
+			return writeJSON(cmd.OutOrStdout(), response)
+		}
+		return runJQ(cmd.OutOrStdout(), query, response)
+	},
+}
+
+// apiPayload builds the request body from --data and the field flags
+func apiPayload(stdin io.Reader) (map[string]any, error) {
+	payload := map[string]any{}
+
+	if apiData != "" {
+		data, err := readArg(apiData, stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading --data: %w", err)
+		}
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, fmt.Errorf("--data must be a JSON object: %w", err)
+		}
+	}
+
+	for _, f := range apiFields {
+		key, value, err := splitField(f)
+		if err != nil {
+			return nil, err
+		}
+		typed, err := typedValue(value, stdin)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", key, err)
+		}
+		payload[key] = typed
+	}
+	for _, f := range apiRawFields {
+		key, value, err := splitField(f)
+		if err != nil {
+			return nil, err
+		}
+		payload[key] = value
+	}
+	return payload, nil
+}
+
+func splitField(f string) (string, string, error) {
+	key, value, ok := strings.Cut(f, "=")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid field %q: expected key=value", f)
+	}
+	return key, value, nil
+}
+
+// typedValue interprets a -F value the way gh api does
+func typedValue(value string, stdin io.Reader) (any, error) {
+	switch value {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, nil
+	}
+	if name, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := readArg(name, stdin)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// readArg reads the contents named by a flag: - for stdin, a path for a
+// file, or for --data a literal JSON document
+func readArg(arg string, stdin io.Reader) ([]byte, error) {
+	switch {
+	case arg == stdioPath:
+		return io.ReadAll(stdin)
+	case strings.HasPrefix(strings.TrimSpace(arg), "{"):
+		return []byte(arg), nil
+	default:
+		return os.ReadFile(strings.TrimPrefix(arg, "@"))
+	}
+}
+
+// callPaginated calls a list method page by page and returns the first
+// page's response with data holding every page's items
+func callPaginated(call func(string, any, any) error, method string, payload map[string]any) (map[string]any, error) {
+	offset, _ := intValue(payload["offset"])
+	limit, ok := intValue(payload["limit"])
+	if !ok || limit <= 0 {
+		limit = apiPageSize
+	}
+
+	var first map[string]any
+	var items []any
+	for {
+		page := make(map[string]any, len(payload)+2)
+		for k, v := range payload {
+			page[k] = v
+		}
+		page["offset"] = offset
+		page["limit"] = limit
+
+		var response map[string]any
+		if err := call(method, page, &response); err != nil {
+			return nil, err
+		}
+		if response == nil {
+			return nil, nil
+		}
+		data, ok := response["data"].([]any)
+		if !ok {
+			return nil, fmt.Errorf("--paginate: %s does not return a list", method)
+		}
+		if first == nil {
+			first = response
+		}
+		items = append(items, data...)
+
+		next, _ := response["pagination"].(map[string]any)
+		if len(data) == 0 || len(data) < limit || next == nil || next["nextPath"] == nil || next["nextPath"] == "" {
+			break
+		}
+		offset += len(data)
+	}
+
+	if items == nil {
+		items = []any{}
+	}
+	first["data"] = items
+	delete(first, "pagination")
+	return first, nil
+}
+
+// intValue reads a number given with -F or in --data
+func intValue(v any) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+func writeJSON(w io.Writer, v any) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encoding response: %w", err)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// runJQ prints each result of query, strings without quotes
+func runJQ(w io.Writer, query *gojq.Query, v any) error {
+	iter := query.Run(v)
+	for {
+		result, ok := iter.Next()
+		if !ok {
+			return nil
+		}
+		if err, ok := result.(error); ok {
+			return fmt.Errorf("--jq: %w", err)
+		}
+		if s, ok := result.(string); ok {
+			if _, err := fmt.Fprintln(w, s); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeJSON(w, result); err != nil {
+			return err
+		}
+	}
+}
+
+func init() {
+	apiCmd.Flags().StringVar(&apiData, "data", "", "JSON request body, @file to read it from a file, or - for stdin")
+	apiCmd.Flags().StringArrayVarP(&apiFields, "field", "F", nil, "add a typed key=value field to the request body")
+	apiCmd.Flags().StringArrayVarP(&apiRawFields, "raw-field", "f", nil, "add a string key=value field to the request body")
+	apiCmd.Flags().BoolVar(&apiPaginate, "paginate", false, "fetch every page of a list method")
+	apiCmd.Flags().StringVar(&apiJQ, "jq", "", "filter the response with a jq expression")
+
+	RootCmd.AddCommand(apiCmd)
+}
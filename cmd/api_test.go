@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+)
+
+func TestAPICommand(t *testing.T) {
+	srv := useFakeServer(t)
+	defer RootCmd.SetErr(nil)
+
+	first := srv.AddDocument(api.Document{Title: "One"})
+	srv.AddDocument(api.Document{Title: "Two"})
+	srv.AddDocument(api.Document{Title: "Three"})
+
+	run := func(args ...string) (string, error) {
+		t.Helper()
+		resetCommands()
+		var out bytes.Buffer
+		RootCmd.SetOut(&out)
+		RootCmd.SetErr(&bytes.Buffer{})
+		RootCmd.SetArgs(append([]string{"api"}, args...))
+		err := RootCmd.Execute()
+		return out.String(), err
+	}
+
+	out, err := run("documents.list", "-F", "limit=2", "--paginate", "--jq", ".data[].title")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Newest first, across both pages
+	if out != "Three\nTwo\nOne\n" {
+		t.Errorf("expected every page's titles, got %q", out)
+	}
+
+	out, err = run("documents.info", "-f", "id="+first.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "\n  \"data\": {") || !strings.Contains(out, `"title": "One"`) {
+		t.Errorf("expected the pretty-printed response, got %q", out)
+	}
+
+	if _, err := run("documents.delete", "-f", "id="+first.ID, "--dry-run"); !errors.Is(err, ErrChangesPending) {
+		t.Errorf("expected the delete to be held back, got %v", err)
+	}
+	if _, ok := srv.Document(first.ID); !ok {
+		t.Error("dry-run deleted the document")
+	}
+
+	if _, err := run("documents.info", "-f", "id=missing"); err == nil || !strings.Contains(err.Error(), "not_found") {
+		t.Errorf("expected the API error, got %v", err)
+	}
+	if _, err := run("../files"); err == nil || !strings.Contains(err.Error(), "invalid method") {
+		t.Errorf("expected an invalid method error, got %v", err)
+	}
+}
+
+func TestAPIPayload(t *testing.T) {
+	resetCommands()
+	defer resetCommands()
+
+	apiData = `{"collectionId": "c1", "limit": 10}`
+	apiFields = []string{"limit=5", "publish=true", "text=@-"}
+	apiRawFields = []string{"title=42"}
+
+	payload, err := apiPayload(strings.NewReader("from stdin"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"collectionId": "c1",
+		"limit":        int64(5),
+		"publish":      true,
+		"text":         "from stdin",
+		"title":        "42",
+	}
+	for k, v := range want {
+		if payload[k] != v {
+			t.Errorf("%s: expected %#v, got %#v", k, v, payload[k])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"outline-cli/api"
+	"outline-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+var attachAppend string
+
+// dryRunAttachmentURL stands in for the URL of an attachment a dry run
+// didn't upload
+const dryRunAttachmentURL = "(dry-run)"
+
+// linkText escapes the characters that would end or format Markdown link
+// text, so any file name can be used as one
+var linkText = strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`, `*`, `\*`, `_`, `\_`, "`", "\\`", "\n", " ")
+
+var attachCmd = &cobra.Command{
+	Use:   "attach [docID|URL] [file]",
+	Short: "Upload a file as an attachment to a document",
+	Long: `Upload a file as an attachment to a document.
+
+With --append link or --append image, a Markdown link or image embed for the
+uploaded file is also appended to the end of the document.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch attachAppend {
+		case "", "none", "link", "image":
+		default:
+			return fmt.Errorf("invalid --append value %q: must be none, link or image", attachAppend)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		client := newClient(cfg)
+		docID, err := resolveDocumentID(client, args[0])
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Base(args[1])
+		att, err := client.CreateAttachment(name, contentTypeOf(args[1], data), data, docID)
+		if err != nil {
+			return fmt.Errorf("uploading attachment: %w", err)
+		}
+		if dryRun {
+			att.URL = dryRunAttachmentURL
+		}
+
+		if attachAppend == "link" || attachAppend == "image" {
+			if err := appendAttachmentLink(client, docID, att); err != nil {
+				return err
+			}
+		}
+
+		return printer.Print(att, "id", "name", "url")
+	},
+}
+
+// appendAttachmentLink adds a Markdown reference to att at the end of a document
+func appendAttachmentLink(client api.Client, docID string, att *api.Attachment) error {
+	doc, err := client.GetDocument(docID)
+	if err != nil {
+		return fmt.Errorf("fetching document: %w", err)
+	}
+
+	url := api.AttachmentURL(att.ID)
+	if dryRun {
+		url = dryRunAttachmentURL
+	}
+	link := fmt.Sprintf("[%s](%s)", linkText.Replace(att.Name), url)
+	if attachAppend == "image" {
+		link = "!" + link
+	}
+
+	text := strings.TrimRight(doc.Text, "\n") + "\n\n" + link + "\n"
+	if err := client.UpdateDocument(docID, text); err != nil {
+		return fmt.Errorf("updating document: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	attachCmd.Flags().StringVar(&attachAppend, "append", "none", "append a reference to the document: none, link or image")
+
+	RootCmd.AddCommand(attachCmd)
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	"outline-cli/api"
+	"outline-cli/assets"
+	"outline-cli/workspace"
+)
+
+// downloadAttachments saves the attachments referenced by text into the
+// assets directory under dir and returns text with links rewritten to the
+// local copies. Attachments already present locally are not downloaded again.
+func downloadAttachments(client api.Client, text string, dir string) (string, error) {
+	ids := assets.RemoteIDs(text)
+	if len(ids) == 0 {
+		return text, nil
+	}
+
+	assetDir := filepath.Join(dir, assets.Dir)
+	if err := os.MkdirAll(assetDir, 0755); err != nil {
+		return "", fmt.Errorf("creating assets directory: %w", err)
+	}
+
+	paths := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if existing, _ := filepath.Glob(filepath.Join(assetDir, id+"*")); len(existing) > 0 {
+			paths[id] = filepath.Base(existing[0])
+			continue
+		}
+
+		file, err := client.DownloadAttachment(id)
+		if err != nil {
+			return "", fmt.Errorf("downloading attachment %s: %w", id, err)
+		}
+
+		name := assets.FileName(id, file.Name, file.ContentType)
+		if err := writeFileAtomic(filepath.Join(assetDir, name), file.Data, 0644); err != nil {
+			return "", fmt.Errorf("writing attachment %s: %w", id, err)
+		}
+		logger.Info("downloaded attachment", "id", id, "path", filepath.Join(assetDir, name))
+		paths[id] = name
+	}
+
+	return assets.Localize(text, paths), nil
+}
+
+// uploadAttachments reverses downloadAttachments and uploads any other
+// local files linked from text, resolved relative to dir, returning text
+// with every link pointing at Outline
+func uploadAttachments(client api.Client, text string, dir string, docID string) (string, error) {
+	text = assets.Unlocalize(text)
+
+	// Only files next to or below the document may be uploaded
+	root, err := workspace.NewRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	return uploadLinkedFiles(client, text, root, "", docID)
+}
+
+// uploadLinkedFiles uploads the local files linked from text, resolved
+// relative to base within root, and points their links at Outline
+func uploadLinkedFiles(client api.Client, text string, root *workspace.Root, base string, docID string) (string, error) {
+	for _, target := range assets.LocalFiles(text) {
+		filename, err := root.Resolve(path.Join(base, target))
+		if err != nil {
+			logger.Warn("linked file is outside the document's directory, leaving link unchanged", "path", target)
+			continue
+		}
+		data, err := os.ReadFile(filename)
+		if errors.Is(err, os.ErrNotExist) {
+			logger.Warn("linked file not found, leaving link unchanged", "path", filename)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("reading attachment: %w", err)
+		}
+
+		att, err := client.CreateAttachment(filepath.Base(filename), contentTypeOf(filename, data), data, docID)
+		if err != nil {
+			return "", fmt.Errorf("uploading %s: %w", filename, err)
+		}
+		logger.Info("uploaded attachment", "id", att.ID, "path", filename)
+
+		text = assets.ReplaceLink(text, target, api.AttachmentURL(att.ID))
+	}
+
+	return text, nil
+}
+
+func contentTypeOf(filename string, data []byte) string {
+	if ct := mime.TypeByExtension(filepath.Ext(filename)); ct != "" {
+		return ct
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,667 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/assets"
+	"outline-cli/backup"
+	"outline-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+var backupOut string
+var backupIncremental string
+var backupArchived bool
+var restoreBackupForce bool
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the whole workspace into an archive",
+	Long: `Back up every collection, document, revision's metadata and attachment
+into a single archive for disaster recovery.
+
+The archive is a zstd-compressed tar file with a manifest naming its format
+version and the instance it came from, and a README describing the layout,
+so it can be read without this tool. Documents are stored as Markdown with
+their metadata alongside. Drafts are always included; archived documents
+only with --archived. Revision text is not kept: Outline can't recreate a
+history, so only who changed what and when is recorded.
+
+With --incremental, only documents changed and attachments added since the
+given backup are stored. Restoring needs every archive in the chain.`,
+	Example: `  outline backup --out workspace.tar.zst --archived
+  outline backup --incremental workspace.tar.zst --out monday.tar.zst`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		source := strings.TrimRight(cfg.OutlineURL, "/")
+
+		var base *backup.Manifest
+		if backupIncremental != "" {
+			ar, err := backup.OpenArchive(backupIncremental)
+			if err != nil {
+				return err
+			}
+			base = ar.Manifest
+			ar.Close()
+			if base.Source != source {
+				return fmt.Errorf("%s is a backup of %s, not %s", backupIncremental, base.Source, source)
+			}
+		}
+
+		manifest := backup.NewManifest(source, time.Now())
+		manifest.IncludesArchived = backupArchived
+		if base != nil {
+			manifest.Base = base.ID
+		}
+		out := backupOut
+		if out == "" {
+			out = "outline-backup-" + manifest.CreatedAt.Format("20060102-150405") + ".tar.zst"
+		}
+
+		spool, err := os.MkdirTemp("", "outline-backup-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(spool)
+
+		b := &workspaceBackup{client: newClient(cfg), manifest: manifest, base: base, spool: spool}
+		if err := b.scan(cmd); err != nil {
+			return err
+		}
+		if err := b.write(out); err != nil {
+			return err
+		}
+		logger.Info("wrote backup", "id", manifest.ID, "path", out)
+
+		entry := statusEntry{
+			ID:     manifest.ID,
+			Action: "backed up",
+			Path:   out,
+			Message: fmt.Sprintf("%d collections, %d documents (%d stored), %d attachments (%d stored)",
+				len(b.collections), len(manifest.Documents), len(b.documents), len(manifest.Attachments), len(b.attachments)),
+		}
+		return printer.Print(entry, "id", "action", "path", "message")
+	},
+}
+
+var restoreBackupCmd = &cobra.Command{
+	Use:   "restore-backup <archive>...",
+	Short: "Recreate the content of a backup on an empty instance",
+	Long: `Recreate the collections, documents and attachments of a backup on an empty
+Outline instance, such as after losing one.
+
+Give a full backup followed by the incremental backups made on top of it,
+oldest first; the content is restored as of the last one. Documents get new
+IDs, so links between them and to attachments are rewritten; drafts stay
+drafts and archived documents are archived again. Revision history and
+authorship can't be recreated and are left behind.
+
+The instance must have no collections unless --force is given.`,
+	Example: `  outline restore-backup workspace.tar.zst
+  outline restore-backup workspace.tar.zst monday.tar.zst tuesday.tar.zst`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		archives := make([]*backup.ArchiveReader, 0, len(args))
+		defer func() {
+			for _, ar := range archives {
+				ar.Close()
+			}
+		}()
+		for i, name := range args {
+			ar, err := backup.OpenArchive(name)
+			if err != nil {
+				return err
+			}
+			archives = append(archives, ar)
+
+			want := ""
+			if i > 0 {
+				want = archives[i-1].Manifest.ID
+			}
+			if got := ar.Manifest.Base; got != want {
+				if want == "" {
+					return fmt.Errorf("%s is incremental against backup %s; give the archives it builds on first, oldest first", name, got)
+				}
+				return fmt.Errorf("%s builds on backup %s, not on %s", name, got, args[i-1])
+			}
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		client := newClient(cfg)
+		if !restoreBackupForce {
+			existing, err := client.ListCollections()
+			if err != nil {
+				return fmt.Errorf("listing collections: %w", err)
+			}
+			if len(existing) > 0 {
+				return fmt.Errorf("the instance already has %d collections; use --force to restore into it anyway", len(existing))
+			}
+		}
+
+		r := &backupRestorer{
+			client:      client,
+			manifest:    archives[len(archives)-1].Manifest,
+			collections: make(map[string]map[string]any),
+			documents:   make(map[string]*restoredDocument),
+			attachments: make(map[string]string),
+			refs:        make(map[string]string),
+		}
+		for i, ar := range archives {
+			if err := r.read(ar); err != nil {
+				return fmt.Errorf("reading %s: %w", args[i], err)
+			}
+		}
+
+		progress := newProgressBar(cmd.ErrOrStderr(), "restore", len(r.manifest.Documents))
+		err = r.restore(progress)
+		progress.finish()
+
+		if printErr := printer.Print(r.entries, "id", "title", "action", "message"); printErr != nil {
+			return printErr
+		}
+		return err
+	},
+}
+
+// Statuses of documents in a backup
+const (
+	statusPublished = "published"
+	statusDraft     = "draft"
+	statusArchived  = "archived"
+)
+
+// workspaceBackup gathers the content of a backup
+type workspaceBackup struct {
+	client   api.Client
+	manifest *backup.Manifest
+	// base is the manifest of the backup this one is incremental against
+	base *backup.Manifest
+	// spool is a directory holding downloaded attachments until they are
+	// written to the archive
+	spool       string
+	collections []map[string]any
+	documents   []backedUpDocument
+	attachments []backedUpAttachment
+}
+
+// backedUpDocument is a document stored in the archive
+type backedUpDocument struct {
+	ID   string
+	Meta map[string]any
+	Text string
+}
+
+// backedUpAttachment is an attachment stored in the archive
+type backedUpAttachment struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ContentType string `json:"contentType"`
+	Size        int    `json:"size"`
+}
+
+// scan lists everything in the workspace and fetches what has to be
+// stored, so the manifest is complete before the archive is written
+func (b *workspaceBackup) scan(cmd *cobra.Command) error {
+	response, err := callPaginated(b.client.Call, "collections.list", map[string]any{})
+	if err != nil {
+		return fmt.Errorf("listing collections: %w", err)
+	}
+	items, _ := response["data"].([]any)
+	for _, item := range items {
+		coll, ok := item.(map[string]any)
+		id, _ := coll["id"].(string)
+		if !ok || id == "" {
+			continue
+		}
+		var tree map[string]any
+		if err := b.client.Call("collections.documents", map[string]string{"id": id}, &tree); err != nil {
+			return fmt.Errorf("fetching document tree of %s: %w", coll["name"], err)
+		}
+		coll["documentStructure"] = tree["data"]
+		b.collections = append(b.collections, coll)
+		b.manifest.Collections = append(b.manifest.Collections, id)
+	}
+
+	lists := []struct{ method, status string }{
+		{"documents.list", statusPublished},
+		{"documents.drafts", statusDraft},
+	}
+	if backupArchived {
+		lists = append(lists, struct{ method, status string }{"documents.archived", statusArchived})
+	}
+	var changed []backedUpDocument
+	for _, list := range lists {
+		response, err := callPaginated(b.client.Call, list.method, map[string]any{})
+		if err != nil {
+			return fmt.Errorf("listing documents: %w", err)
+		}
+		items, _ := response["data"].([]any)
+		for _, item := range items {
+			doc, ok := item.(map[string]any)
+			id, _ := doc["id"].(string)
+			if _, seen := b.manifest.Documents[id]; !ok || id == "" || seen {
+				continue
+			}
+			text, _ := doc["text"].(string)
+			delete(doc, "text")
+			doc["status"] = list.status
+
+			entry := backup.DocumentEntry{Status: list.status, Attachments: assets.RemoteIDs(text)}
+			if updatedAt, ok := doc["updatedAt"].(string); ok {
+				entry.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+			}
+			b.manifest.Documents[id] = entry
+			if b.base != nil {
+				prev, ok := b.base.Documents[id]
+				if ok && prev.Status == entry.Status && prev.UpdatedAt.Equal(entry.UpdatedAt) {
+					continue
+				}
+			}
+			changed = append(changed, backedUpDocument{ID: id, Meta: doc, Text: text})
+		}
+	}
+
+	// Attachments still referenced are carried over from the base, the
+	// rest are downloaded
+	inBase := make(map[string]bool)
+	if b.base != nil {
+		for _, id := range b.base.Attachments {
+			inBase[id] = true
+		}
+	}
+	seen := make(map[string]bool)
+	var referenced []string
+	for _, entry := range b.manifest.Documents {
+		for _, id := range entry.Attachments {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			if inBase[id] {
+				b.manifest.Attachments = append(b.manifest.Attachments, id)
+			} else {
+				referenced = append(referenced, id)
+			}
+		}
+	}
+	sort.Strings(referenced)
+
+	progress := newProgressBar(cmd.ErrOrStderr(), "backup", len(changed)+len(referenced))
+	defer progress.finish()
+
+	for _, doc := range changed {
+		response, err := callPaginated(b.client.Call, "revisions.list", map[string]any{"documentId": doc.ID})
+		if err != nil {
+			return fmt.Errorf("listing revisions of %s: %w", doc.Meta["title"], err)
+		}
+		revisions, _ := response["data"].([]any)
+		for _, rev := range revisions {
+			if rev, ok := rev.(map[string]any); ok {
+				delete(rev, "text")
+				delete(rev, "data")
+			}
+		}
+		doc.Meta["revisions"] = revisions
+		b.documents = append(b.documents, doc)
+		progress.increment()
+	}
+
+	for _, id := range referenced {
+		file, err := b.client.DownloadAttachment(id)
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			// Left out of the manifest so the next backup tries again
+			logger.Warn("attachment not found, skipping", "id", id)
+			progress.increment()
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("downloading attachment %s: %w", id, err)
+		}
+		if err := os.WriteFile(filepath.Join(b.spool, fmt.Sprint(len(b.attachments))), file.Data, 0600); err != nil {
+			return err
+		}
+		b.attachments = append(b.attachments, backedUpAttachment{ID: id, Name: file.Name, ContentType: file.ContentType, Size: len(file.Data)})
+		b.manifest.Attachments = append(b.manifest.Attachments, id)
+		progress.increment()
+	}
+	sort.Strings(b.manifest.Attachments)
+	return nil
+}
+
+// write writes the archive to a temporary file renamed to out once
+// complete, so a failed backup never leaves a truncated archive behind
+func (b *workspaceBackup) write(out string) error {
+	f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", out, err)
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	aw, err := backup.NewArchiveWriter(f, b.manifest)
+	if err != nil {
+		return err
+	}
+	for _, coll := range b.collections {
+		if err := aw.WriteJSON("collections/"+coll["id"].(string)+".json", coll); err != nil {
+			return err
+		}
+	}
+	for _, doc := range b.documents {
+		if err := aw.WriteJSON("documents/"+doc.ID+".json", doc.Meta); err != nil {
+			return err
+		}
+		if err := aw.WriteFile("documents/"+doc.ID+".md", []byte(doc.Text)); err != nil {
+			return err
+		}
+	}
+	for i, att := range b.attachments {
+		data, err := os.ReadFile(filepath.Join(b.spool, fmt.Sprint(i)))
+		if err != nil {
+			return err
+		}
+		if err := aw.WriteJSON("attachments/"+att.ID+".json", att); err != nil {
+			return err
+		}
+		if err := aw.WriteFile("attachments/"+att.ID+".data", data); err != nil {
+			return err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", out, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing %s: %w", out, err)
+	}
+	return os.Rename(tmp, out)
+}
+
+// backupRestorer recreates the content of a chain of backups
+type backupRestorer struct {
+	client   api.Client
+	manifest *backup.Manifest
+	// collections holds the backed-up collections by ID
+	collections map[string]map[string]any
+	documents   map[string]*restoredDocument
+	// attachments maps backed-up attachment IDs to their uploads
+	attachments map[string]string
+	// wanted holds the attachments of the last backup in the chain
+	wanted map[string]bool
+	// refs maps the IDs and url-ids of backed-up documents to their IDs
+	refs    map[string]string
+	order   []string
+	entries []statusEntry
+}
+
+// restoredDocument is a backed-up document and its copy once created
+type restoredDocument struct {
+	Meta    map[string]any
+	Text    string
+	ID      string
+	URLID   string
+	created bool
+}
+
+// read takes the content of an archive, replacing what earlier archives in
+// the chain held. Attachments are uploaded as they are found, as there may
+// be too many to hold in memory.
+func (r *backupRestorer) read(ar *backup.ArchiveReader) error {
+	pending := make(map[string]backedUpAttachment)
+	if r.wanted == nil {
+		r.wanted = make(map[string]bool)
+		for _, id := range r.manifest.Attachments {
+			r.wanted[id] = true
+		}
+	}
+	for {
+		name, rd, err := ar.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		dir, file := path.Split(name)
+		id, ext := strings.TrimSuffix(file, path.Ext(file)), path.Ext(file)
+
+		switch {
+		case dir == "collections/" && ext == ".json":
+			var coll map[string]any
+			if err := decodeEntry(rd, &coll); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			r.collections[id] = coll
+		case dir == "documents/" && (ext == ".json" || ext == ".md"):
+			if _, ok := r.manifest.Documents[id]; !ok {
+				continue
+			}
+			doc := r.documents[id]
+			if doc == nil {
+				doc = &restoredDocument{}
+				r.documents[id] = doc
+			}
+			if ext == ".md" {
+				data, err := io.ReadAll(rd)
+				if err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+				doc.Text = string(data)
+			} else if err := decodeEntry(rd, &doc.Meta); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		case dir == "attachments/" && ext == ".json":
+			var att backedUpAttachment
+			if err := decodeEntry(rd, &att); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			pending[id] = att
+		case dir == "attachments/" && ext == ".data":
+			att, ok := pending[id]
+			if _, done := r.attachments[id]; !ok || done || !r.wanted[id] {
+				continue
+			}
+			data, err := io.ReadAll(rd)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			uploaded, err := r.client.CreateAttachment(att.Name, att.ContentType, data, "")
+			if err != nil {
+				return fmt.Errorf("uploading attachment %s: %w", att.Name, err)
+			}
+			r.attachments[id] = uploaded.ID
+		}
+	}
+}
+
+// restore creates the collections and documents read, then fixes up links
+// between documents and archives those that were archived
+func (r *backupRestorer) restore(progress *progressBar) error {
+	for id := range r.manifest.Documents {
+		doc := r.documents[id]
+		if doc == nil || doc.Meta == nil {
+			return fmt.Errorf("document %s is missing from the archives; give every backup in the chain", id)
+		}
+		r.refs[id] = id
+		if urlID, ok := doc.Meta["urlId"].(string); ok && urlID != "" {
+			r.refs[urlID] = id
+		}
+	}
+
+	collectionIDs := make(map[string]string)
+	for _, id := range r.manifest.Collections {
+		coll := r.collections[id]
+		if coll == nil {
+			return fmt.Errorf("collection %s is missing from the archives", id)
+		}
+		var settings api.Collection
+		if data, err := json.Marshal(coll); err != nil || json.Unmarshal(data, &settings) != nil {
+			return fmt.Errorf("collection %s has unreadable settings", id)
+		}
+		var response struct {
+			Data api.Collection `json:"data"`
+		}
+		if err := r.client.Call("collections.create", collectionPayload(settings), &response); err != nil {
+			return fmt.Errorf("creating collection %s: %w", settings.Name, err)
+		}
+		collectionIDs[id] = response.Data.ID
+		r.entries = append(r.entries, dryRunStatus(statusEntry{ID: response.Data.ID, Title: settings.Name, Action: "created", Message: "collection"}))
+	}
+
+	// Documents in the collection trees go in their order there, then the
+	// drafts and archived documents the trees leave out
+	var ids []string
+	for _, id := range r.manifest.Collections {
+		ids = append(ids, structureIDs(r.collections[id]["documentStructure"])...)
+	}
+	var rest []string
+	for id := range r.manifest.Documents {
+		rest = append(rest, id)
+	}
+	sort.Strings(rest)
+	ids = append(ids, rest...)
+
+	for _, id := range ids {
+		if err := r.create(id, collectionIDs, progress); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		return nil
+	}
+
+	for _, id := range r.order {
+		doc := r.documents[id]
+		text := assets.Relink(doc.Text, r.attachments)
+		relinked, _ := relinkDocuments(text, r.manifest.Source, func(ref string) (string, bool) {
+			if target, ok := r.refs[ref]; ok {
+				return r.documents[target].URLID, true
+			}
+			return "", false
+		})
+		if relinked != text {
+			if err := r.client.UpdateDocument(doc.ID, relinked); err != nil {
+				return fmt.Errorf("rewriting links in %s: %w", doc.Meta["title"], err)
+			}
+		}
+	}
+	for _, id := range r.order {
+		if r.manifest.Documents[id].Status != statusArchived {
+			continue
+		}
+		if err := r.client.Call("documents.archive", map[string]string{"id": r.documents[id].ID}, nil); err != nil {
+			return fmt.Errorf("archiving %s: %w", r.documents[id].Meta["title"], err)
+		}
+	}
+	return nil
+}
+
+// create creates the document with the given backed-up ID, after its
+// parent, unless it already exists
+func (r *backupRestorer) create(id string, collectionIDs map[string]string, progress *progressBar) error {
+	doc, ok := r.documents[id]
+	if !ok || doc.created {
+		return nil
+	}
+	parentID := ""
+	if parent, _ := doc.Meta["parentDocumentId"].(string); parent != "" {
+		if err := r.create(parent, collectionIDs, progress); err != nil {
+			return err
+		}
+		if p, ok := r.documents[parent]; ok {
+			parentID = p.ID
+		}
+	}
+
+	title, _ := doc.Meta["title"].(string)
+	collectionID, _ := doc.Meta["collectionId"].(string)
+	status := r.manifest.Documents[id].Status
+	payload := map[string]any{
+		"title":   title,
+		"text":    assets.Relink(doc.Text, r.attachments),
+		"publish": status != statusDraft,
+	}
+	if collectionIDs[collectionID] != "" {
+		payload["collectionId"] = collectionIDs[collectionID]
+	}
+	if parentID != "" {
+		payload["parentDocumentId"] = parentID
+	}
+	var response struct {
+		Data api.Document `json:"data"`
+	}
+	if err := r.client.Call("documents.create", payload, &response); err != nil {
+		r.entries = append(r.entries, statusEntry{ID: id, Title: title, Action: "failed", Message: err.Error()})
+		return fmt.Errorf("restoring %s: %w", title, err)
+	}
+	doc.ID, doc.URLID, doc.created = response.Data.ID, response.Data.URLID, true
+
+	// The icon and emoji are set once the document exists
+	icon, _ := doc.Meta["icon"].(string)
+	emoji, _ := doc.Meta["emoji"].(string)
+	if icon != "" || emoji != "" {
+		if err := r.client.Call("documents.update", map[string]string{"id": doc.ID, "icon": icon, "emoji": emoji}, nil); err != nil {
+			return fmt.Errorf("setting the icon of %s: %w", title, err)
+		}
+	}
+	r.order = append(r.order, id)
+	r.entries = append(r.entries, dryRunStatus(statusEntry{ID: doc.ID, Title: title, Action: "restored", Message: status}))
+	progress.increment()
+	return nil
+}
+
+// structureIDs lists the document IDs in a backed-up document tree, parents
+// before their children
+func structureIDs(structure any) []string {
+	nodes, _ := structure.([]any)
+	var ids []string
+	for _, n := range nodes {
+		node, ok := n.(map[string]any)
+		if !ok {
+			continue
+		}
+		if id, ok := node["id"].(string); ok {
+			ids = append(ids, id)
+		}
+		ids = append(ids, structureIDs(node["children"])...)
+	}
+	return ids
+}
+
+func decodeEntry(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func init() {
+	backupCmd.Flags().StringVarP(&backupOut, "out", "o", "", "archive to write (default outline-backup-<time>.tar.zst)")
+	backupCmd.Flags().StringVar(&backupIncremental, "incremental", "", "only store what changed since this earlier backup")
+	backupCmd.Flags().BoolVar(&backupArchived, "archived", false, "include archived documents")
+	restoreBackupCmd.Flags().BoolVar(&restoreBackupForce, "force", false, "restore into an instance that already has collections")
+
+	RootCmd.AddCommand(backupCmd)
+	RootCmd.AddCommand(restoreBackupCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/backup"
+	"outline-cli/outlinetest"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	target := useFakeServer(t)
+	source := useFakeServer(t)
+
+	runbooks := source.AddCollection("Runbooks")
+	att := source.AddAttachment("chart.png", "image/png", []byte("chart"), "")
+	parent := source.AddDocument(api.Document{Title: "Databases", Text: "![chart](" + att.URL + ")", CollectionID: runbooks.ID})
+	child := source.AddDocument(api.Document{Title: "Failover", Text: "Steps", CollectionID: runbooks.ID, ParentDocumentID: parent.ID})
+	source.AddDraft(api.Document{Title: "Ideas", Text: "See [failover](/doc/failover-" + child.URLID + ")", CollectionID: runbooks.ID})
+	old := source.AddDocument(api.Document{Title: "Legacy", Text: "Gone", CollectionID: runbooks.ID})
+	source.Archive(old.ID)
+	client := api.DefaultClientFactory(source.Config())
+	if err := client.Call("collections.update", map[string]string{"id": runbooks.ID, "color": "#FF5733", "icon": "server", "permission": "read"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call("documents.update", map[string]string{"id": child.ID, "icon": "🔥"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	full := filepath.Join(dir, "full.tar.zst")
+	RootCmd.SetArgs([]string{"backup", "--archived", "--out", full})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updateText(t, source, child.ID, "Updated steps")
+	incremental := filepath.Join(dir, "incremental.tar.zst")
+	resetCommands()
+	RootCmd.SetArgs([]string{"backup", "--archived", "--incremental", full, "--out", incremental})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ar, err := backup.OpenArchive(incremental)
+	if err != nil {
+		t.Fatalf("opening incremental backup: %v", err)
+	}
+	var stored []string
+	for {
+		name, _, err := ar.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading incremental backup: %v", err)
+		}
+		if strings.HasPrefix(name, "documents/") || strings.HasPrefix(name, "attachments/") {
+			stored = append(stored, name)
+		}
+	}
+	ar.Close()
+	if len(ar.Manifest.Documents) != 4 || len(ar.Manifest.Attachments) != 1 {
+		t.Errorf("expected the manifest to list everything, got %+v", ar.Manifest)
+	}
+	if len(stored) != 2 || stored[0] != "documents/"+child.ID+".json" {
+		t.Errorf("expected only the changed document stored, got %v", stored)
+	}
+
+	targetCfg := target.Config()
+	targetCfg.Backups.Disabled = true
+	useConfig(t, targetCfg)
+	RootCmd.SetArgs([]string{"restore-backup", incremental})
+	if err := RootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "oldest first") {
+		t.Fatalf("expected restoring an incremental backup alone to fail, got %v", err)
+	}
+	resetCommands()
+	RootCmd.SetArgs([]string{"restore-backup", full, incremental})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	collections := target.Collections()
+	if len(collections) != 1 || collections[0].Name != "Runbooks" {
+		t.Fatalf("expected Runbooks restored, got %+v", collections)
+	}
+	if c := collections[0]; c.Color != "#FF5733" || c.Icon != "server" || c.Permission != "read" {
+		t.Errorf("expected the collection's settings restored, got %+v", c)
+	}
+	restored := make(map[string]api.Document)
+	for _, doc := range target.Documents() {
+		restored[doc.Title] = doc
+	}
+	databases, failover, ideas, legacy := restored["Databases"], restored["Failover"], restored["Ideas"], restored["Legacy"]
+	if len(restored) != 4 || failover.ParentDocumentID != databases.ID {
+		t.Fatalf("expected the hierarchy restored, got %+v", restored)
+	}
+	if failover.Text != "Updated steps" {
+		t.Errorf("expected the text of the incremental backup, got %q", failover.Text)
+	}
+	if failover.Icon != "🔥" {
+		t.Errorf("expected the document's icon restored, got %q", failover.Icon)
+	}
+	if ideas.Text != "See [failover](/doc/"+failover.URLID+")" {
+		t.Errorf("expected the link rewritten to the restored document, got %q", ideas.Text)
+	}
+	if target.Status(ideas.ID) != outlinetest.StatusDraft || target.Status(legacy.ID) != outlinetest.StatusArchived {
+		t.Errorf("expected the draft and archived document restored as such, got %s and %s", target.Status(ideas.ID), target.Status(legacy.ID))
+	}
+	ids := strings.Split(databases.Text, "attachments.redirect?id=")
+	if len(ids) != 2 {
+		t.Fatalf("expected one attachment link, got %q", databases.Text)
+	}
+	if copied, ok := target.Attachment(strings.TrimSuffix(ids[1], ")")); !ok || string(copied.Data) != "chart" {
+		t.Errorf("expected the attachment uploaded, got %+v", copied)
+	}
+
+	resetCommands()
+	RootCmd.SetArgs([]string{"restore-backup", full})
+	if err := RootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected restoring into a non-empty instance to fail, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/backup"
+	"outline-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+// backupClient snapshots a document's remote content before any call that
+// overwrites or deletes it
+type backupClient struct {
+	api.Client
+	cfg *config.Config
+
+	once  sync.Once
+	store *backup.Store
+	err   error
+}
+
+func (b *backupClient) UpdateDocument(docID string, content string) error {
+	if err := b.snapshot(docID, content); err != nil {
+		return err
+	}
+	return b.Client.UpdateDocument(docID, content)
+}
+
+func (b *backupClient) DeleteDocument(docID string) error {
+	if err := b.snapshot(docID, ""); err != nil {
+		return err
+	}
+	return b.Client.DeleteDocument(docID)
+}
+
+// Call snapshots the document behind a raw documents.update that sets its
+// text, or a documents.delete, as outline api can send either
+func (b *backupClient) Call(method string, payload any, out any) error {
+	if method == "documents.update" || method == "documents.delete" {
+		var target struct {
+			ID   string  `json:"id"`
+			Text *string `json:"text"`
+		}
+		if data, err := json.Marshal(payload); err == nil && json.Unmarshal(data, &target) == nil && target.ID != "" {
+			if method == "documents.delete" || target.Text != nil {
+				replacement := ""
+				if target.Text != nil {
+					replacement = *target.Text
+				}
+				if err := b.snapshot(target.ID, replacement); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return b.Client.Call(method, payload, out)
+}
+
+// snapshot saves the current remote text unless it already equals
+// replacement. A failed backup aborts the overwrite.
+func (b *backupClient) snapshot(docID string, replacement string) error {
+	store, err := b.backupStore()
+	if err != nil {
+		return err
+	}
+
+	current, err := b.Client.GetDocument(docID)
+	if err != nil {
+		return fmt.Errorf("fetching document for backup: %w", err)
+	}
+	if current.Text == replacement {
+		return nil
+	}
+
+	snap, err := store.Save(current)
+	if err != nil {
+		return fmt.Errorf("backing up document: %w", err)
+	}
+	logger.Info("backed up remote document", "id", docID, "snapshot", snap.Name)
+	return nil
+}
+
+func (b *backupClient) backupStore() (*backup.Store, error) {
+	b.once.Do(func() {
+		b.store, b.err = openBackupStore(b.cfg)
+	})
+	return b.store, b.err
+}
+
+// openBackupStore opens the backup store described by cfg
+func openBackupStore(cfg *config.Config) (*backup.Store, error) {
+	dir := cfg.Backups.Dir
+	if dir == "" {
+		base, err := config.Dir()
+		if err != nil {
+			return nil, fmt.Errorf("locating backup directory: %w", err)
+		}
+		dir = filepath.Join(base, "backups")
+	}
+	maxAge := time.Duration(cfg.Backups.MaxAgeDays) * 24 * time.Hour
+	return backup.NewStore(dir, cfg.Backups.Keep, maxAge), nil
+}
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Inspect and restore automatic backups of remote content",
+	Long: `Every command that overwrites or deletes a document first saves its
+current remote content as a local snapshot, keyed by document ID and time.
+Snapshots are kept in ~/.outline-cli/backups unless "backups.dir" is set in
+the config file; "backups.keep" and "backups.max_age_days" control retention
+and "backups.disabled" turns them off.`,
+}
+
+var backupsListCmd = &cobra.Command{
+	Use:   "list [docID|URL]",
+	Short: "List snapshots, optionally for a single document",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if titleLookup != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MaximumNArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		store, err := openBackupStore(cfg)
+		if err != nil {
+			return err
+		}
+
+		var docID string
+		if titleLookup != "" || len(args) == 1 {
+			docID, err = documentID(cmd, newClient(cfg), args)
+			if err != nil {
+				return err
+			}
+		}
+		snapshots, err := store.List(docID)
+		if err != nil {
+			return err
+		}
+		return printer.Print(snapshots, "documentId", "snapshot", "title", "version", "savedAt")
+	},
+}
+
+var backupsShowCmd = &cobra.Command{
+	Use:   "show [docID|URL] [snapshot]",
+	Short: "Print a snapshot's text, the latest one by default",
+	Args:  documentArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		snap, err := loadSnapshot(cmd, cfg, newClient(cfg), args)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(cmd.OutOrStdout(), snap.Text)
+		return err
+	},
+}
+
+var backupsRestoreCmd = &cobra.Command{
+	Use:   "restore [docID|URL] [snapshot]",
+	Short: "Push a snapshot back to Outline, the latest one by default",
+	Long: `Push a snapshot back to Outline, the latest one by default.
+
+The content being replaced is itself backed up first, so a restore can be
+undone with another restore.`,
+	Args: documentArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		snap, err := loadSnapshot(cmd, cfg, client, args)
+		if err != nil {
+			return err
+		}
+
+		if err := client.UpdateDocument(snap.DocumentID, snap.Text); err != nil {
+			return fmt.Errorf("updating document: %w", err)
+		}
+
+		return printer.Print(dryRunStatus(statusEntry{
+			ID:      snap.DocumentID,
+			Title:   snap.Title,
+			Action:  "restored",
+			Message: "snapshot " + snap.Name,
+		}), "id", "action", "message")
+	},
+}
+
+// loadSnapshot finds the snapshot named by [docID|URL] [snapshot]
+// arguments, or by --title and an optional [snapshot]
+func loadSnapshot(cmd *cobra.Command, cfg *config.Config, client api.Client, args []string) (*backup.Snapshot, error) {
+	store, err := openBackupStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	docID, err := documentID(cmd, client, args)
+	if err != nil {
+		return nil, err
+	}
+	if titleLookup == "" {
+		args = args[1:]
+	}
+	if len(args) == 1 {
+		return store.Load(docID, args[0])
+	}
+	return store.Latest(docID)
+}
+
+func init() {
+	backupsListCmd.Flags().StringVar(&titleLookup, "title", "", "list snapshots of the document with this exact title")
+	backupsShowCmd.Flags().StringVar(&titleLookup, "title", "", "show snapshots of the document with this exact title")
+	backupsRestoreCmd.Flags().StringVar(&titleLookup, "title", "", "restore the document with this exact title")
+
+	backupsCmd.AddCommand(backupsListCmd)
+	backupsCmd.AddCommand(backupsShowCmd)
+	backupsCmd.AddCommand(backupsRestoreCmd)
+
+	RootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var bulkConcurrency int
+
+// runBulk applies fn to every ID using a bounded pool of workers. A failure
+// is recorded against its document and does not stop the others. A summary
+// of every result is printed once all documents are done.
+func runBulk(cmd *cobra.Command, verb string, ids []string, fn func(id string) (statusEntry, error)) error {
+	workers := bulkConcurrency
+	if workers < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	workers = min(workers, len(ids))
+
+	results := make([]statusEntry, len(ids))
+	progress := newProgressBar(cmd.ErrOrStderr(), verb, len(ids))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				entry, err := fn(ids[i])
+				if err != nil {
+					logger.Error(verb+" failed", "id", ids[i], "error", err)
+					entry = statusEntry{ID: ids[i], Action: "failed", Message: err.Error()}
+				}
+				results[i] = entry
+				progress.increment()
+			}
+		}()
+	}
+	for i := range ids {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	progress.finish()
+
+	if err := printer.Print(results, "id", "action", "path", "message"); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, r := range results {
+		if r.Action == "failed" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d documents failed to %s", failed, len(ids), verb)
+	}
+	return nil
+}
+
+// progressBar draws a single-line progress indicator on terminals and
+// stays silent otherwise, so redirected stderr isn't cluttered
+type progressBar struct {
+	mu    sync.Mutex
+	w     io.Writer
+	label string
+	total int
+	done  int
+}
+
+func newProgressBar(w io.Writer, label string, total int) *progressBar {
+	if !isTerminal(w) {
+		w = io.Discard
+	}
+	p := &progressBar{w: w, label: label, total: total}
+	p.draw()
+	return p
+}
+
+func (p *progressBar) increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.draw()
+}
+
+func (p *progressBar) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.w, "\r\033[K")
+}
+
+func (p *progressBar) draw() {
+	const width = 30
+	filled := width
+	if p.total > 0 {
+		filled = width * p.done / p.total
+	}
+	fmt.Fprintf(p.w, "\r%s [%s%s] %d/%d", p.label, strings.Repeat("#", filled), strings.Repeat(" ", width-filled), p.done, p.total)
+}
+
+// isTerminal reports whether a reader or writer is an interactive terminal
+func isTerminal(rw any) bool {
+	f, ok := rw.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
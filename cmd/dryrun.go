@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"outline-cli/api"
+)
+
+// ErrChangesPending is returned after a dry run that would have changed
+// something, so callers can tell it apart from a no-op
+var ErrChangesPending = errors.New("dry run: changes would be made")
+
+var dryRun bool
+
+// pendingChanges counts the mutations held back during a dry run
+var pendingChanges atomic.Int64
+
+var dryRunMu sync.Mutex
+
+// reportMutation prints a request that dry-run mode did not send
+func reportMutation(m api.Mutation) {
+	pendingChanges.Add(1)
+
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	fmt.Fprintf(RootCmd.ErrOrStderr(), "dry run: would call %s\n%s\n", m.Method, api.FormatMutation(m))
+}
+
+// dryRunStatus marks entry as not actually applied when in dry-run mode
+func dryRunStatus(entry statusEntry) statusEntry {
+	if dryRun {
+		entry.Message = "dry run, not sent"
+	}
+	return entry
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"outline-cli/assets"
+	"outline-cli/config"
+	"outline-cli/diff"
+	"outline-cli/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+var editInPlace bool
+var editForce bool
+
+var editCmd = &cobra.Command{
+	Use:   "edit [docID|URL]",
+	Short: "Edit a document in $VISUAL or $EDITOR",
+	Long: `Fetch a document, open it in $VISUAL or $EDITOR, and push it back.
+
+The document is written to a temporary file unless --in-place is given, in
+which case <docID>.md in the working directory is edited instead. When the
+editor exits the changes are shown as a diff and pushed only if the content
+changed. If someone else updated the document in the meantime the push is
+refused and the edited file is kept; use --force to overwrite their changes.`,
+	Args: documentArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		editor, err := editorCommand()
+		if err != nil {
+			return err
+		}
+
+		client := newClient(cfg)
+		docID, err := documentID(cmd, client, args)
+		if err != nil {
+			return err
+		}
+
+		doc, err := client.GetDocument(docID)
+		if err != nil {
+			return fmt.Errorf("fetching document: %w", err)
+		}
+
+		filename, cleanup, err := prepareEditFile(docID, doc.Text)
+		if err != nil {
+			return err
+		}
+		// The temp file is removed however the command ends, unless an
+		// error tells the user their edits are saved in it
+		keep := false
+		defer func() {
+			if !keep {
+				cleanup()
+			}
+		}()
+		keepEdits := func(err error) error {
+			keep = true
+			return err
+		}
+
+		if err := runEditor(editor, filename); err != nil {
+			return keepEdits(err)
+		}
+
+		edited, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("reading edited file: %w", err)
+		}
+
+		// New local files would change the text once uploaded, so comparing
+		// before uploading anything is enough
+		if assets.Unlocalize(string(edited)) == doc.Text {
+			return printer.Print(statusEntry{
+				ID:      docID,
+				Title:   doc.Title,
+				Action:  "unchanged",
+				Message: "no changes to push",
+			}, "id", "action", "message")
+		}
+
+		// Make sure nobody else changed the document while it was being
+		// edited, before uploading attachments for a push that won't happen
+		current, err := client.GetDocument(docID)
+		if err != nil {
+			return fmt.Errorf("checking remote version: %w", err)
+		}
+		if current.Version != doc.Version && !editForce {
+			return keepEdits(fmt.Errorf("document %s was changed remotely (version %d -> %d) while editing; your edits are saved in %s, use --force to overwrite",
+				docID, doc.Version, current.Version, filename))
+		}
+
+		after, err := uploadAttachments(client, string(edited), filepath.Dir(filename), docID)
+		if err != nil {
+			return keepEdits(fmt.Errorf("%w (your edits are saved in %s)", err, filename))
+		}
+
+		fmt.Fprint(cmd.ErrOrStderr(), diff.Unified("remote/"+docID, "local/"+docID, doc.Text, after))
+
+		if err := client.UpdateDocument(docID, after); err != nil {
+			return keepEdits(fmt.Errorf("updating document: %w (your edits are saved in %s)", err, filename))
+		}
+
+		if editInPlace && !dryRun {
+			if err := recordPulled(filename, edited); err != nil {
+				return err
+			}
+		}
+
+		return printer.Print(dryRunStatus(statusEntry{
+			ID:     docID,
+			Title:  doc.Title,
+			Action: "pushed",
+			Path:   filename,
+		}), "id", "action", "path", "message")
+	},
+}
+
+// editorCommand returns the user's preferred editor split into arguments
+func editorCommand() ([]string, error) {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields, nil
+		}
+	}
+	if path, err := exec.LookPath("vi"); err == nil {
+		return []string{path}, nil
+	}
+	return nil, errors.New("no editor configured: set $VISUAL or $EDITOR")
+}
+
+// prepareEditFile returns the file to edit and a function that removes it
+// when it is a temporary copy. In-place edits keep any existing local text.
+func prepareEditFile(docID string, text string) (string, func(), error) {
+	if editInPlace {
+		filename, err := workspace.DocumentFile(docID)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+			if err := writeFileAtomic(filename, []byte(text), 0644); err != nil {
+				return "", nil, fmt.Errorf("writing file: %w", err)
+			}
+			if err := recordPulled(filename, []byte(text)); err != nil {
+				return "", nil, err
+			}
+		} else if err != nil {
+			return "", nil, fmt.Errorf("checking file: %w", err)
+		}
+		return filename, func() {}, nil
+	}
+
+	f, err := os.CreateTemp("", "outline-"+docID+"-*.md")
+	if err != nil {
+		return "", nil, fmt.Errorf("creating temp file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(text); err != nil {
+		os.Remove(f.Name())
+		return "", nil, fmt.Errorf("writing temp file: %w", err)
+	}
+	return f.Name(), func() { os.Remove(f.Name()) }, nil
+}
+
+func runEditor(editor []string, filename string) error {
+	c := exec.Command(editor[0], append(editor[1:], filename)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("running editor %s: %w (edits, if any, are in %s)", editor[0], err, filename)
+	}
+	return nil
+}
+
+func init() {
+	editCmd.Flags().BoolVar(&editInPlace, "in-place", false, "edit <docID>.md in the working directory instead of a temp file")
+	editCmd.Flags().BoolVar(&editForce, "force", false, "push even if the document changed remotely while editing")
+	editCmd.Flags().StringVar(&titleLookup, "title", "", "edit the document with this exact title")
+
+	RootCmd.AddCommand(editCmd)
+}
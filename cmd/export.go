@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"outline-cli/api"
+	"outline-cli/config"
+	"outline-cli/site"
+	"outline-cli/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+var exportOut string
+var exportSiteTitle string
+var exportCollection string
+var exportFormat string
+var exportFile string
+var exportUnpack bool
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export documents out of Outline",
+	Long: `Export a collection, or the whole workspace, as an archive built by Outline.
+
+Outline prepares the archive in the background; the export is started, polled
+until it completes and the zip is downloaded. With --unpack it is also
+extracted into a directory named after the archive, runbooks/ for
+runbooks.zip or runbooks.d/ for an --out without an extension. The directory
+must be empty or not exist yet.
+
+Formats are markdown, json and html. The site subcommand renders a static
+HTML site locally instead.`,
+	Example: `  outline export --collection Runbooks --format markdown --unpack
+  outline export --format json --out workspace.zip`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, ok := archiveFormats[exportFormat]
+		if !ok {
+			return fmt.Errorf("--format must be markdown, json or html, not %q", exportFormat)
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		name, collectionID := "outline-export", ""
+		if exportCollection != "" {
+			collections, err := client.ListCollections()
+			if err != nil {
+				return fmt.Errorf("listing collections: %w", err)
+			}
+			selected, err := selectCollections(collections, []string{exportCollection})
+			if err != nil {
+				return err
+			}
+			name, collectionID = selected[0].Name, selected[0].ID
+		}
+		out := exportFile
+		if out == "" {
+			out = workspace.Slugify(name) + ".zip"
+		}
+		dir := unpackDir(out)
+		if exportUnpack {
+			if err := checkUnpackDir(dir); err != nil {
+				return err
+			}
+		}
+
+		op, err := client.ExportCollection(collectionID, format)
+		if err != nil {
+			return fmt.Errorf("starting export: %w", err)
+		}
+		entry := statusEntry{ID: op.ID, Title: name, Action: "exported", Path: out}
+		if dryRun {
+			return printer.Print(dryRunStatus(entry), "id", "action", "path", "message")
+		}
+
+		op, err = waitForFileOperation(cmd, client, op, "export")
+		if err != nil {
+			return err
+		}
+		if err := downloadArchive(client, op.ID, out); err != nil {
+			return err
+		}
+		logger.Info("downloaded export", "id", op.ID, "path", out)
+
+		if exportUnpack {
+			if err := unpackArchive(out, dir); err != nil {
+				return err
+			}
+			entry.Message = "unpacked into " + dir
+		}
+		return printer.Print(entry, "id", "action", "path", "message")
+	},
+}
+
+var exportSiteCmd = &cobra.Command{
+	Use:   "site [collection...]",
+	Short: "Export collections as a static HTML site",
+	Long: `Export collections as a static HTML site that can be browsed offline.
+
+Every document becomes a page, laid out like sync lays out files, with a
+sidebar that follows the document hierarchy. Links between exported
+documents become relative links, attachments are downloaded into assets/,
+and a search index lets the site be searched without a server, including
+when opened from file:// URLs.
+
+Collections are named by ID, url-id or name; without arguments the whole
+workspace is exported.`,
+	Example: `  outline export site Runbooks --out ./runbooks-site
+  outline export site --out ./wiki --title "Engineering Wiki"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		collections, err := client.ListCollections()
+		if err != nil {
+			return fmt.Errorf("listing collections: %w", err)
+		}
+		collections, err = selectCollections(collections, args)
+		if err != nil {
+			return err
+		}
+
+		title := exportSiteTitle
+		if title == "" {
+			title = "Outline"
+			if len(collections) == 1 {
+				title = collections[0].Name
+			}
+		}
+		s := &site.Site{Title: title, BaseURL: cfg.OutlineURL}
+
+		var ids []string
+		trees := make([][]api.NavigationNode, len(collections))
+		layout := make(map[string]string)
+		for i, coll := range collections {
+			trees[i], err = client.CollectionDocuments(coll.ID)
+			if err != nil {
+				return fmt.Errorf("fetching document tree of %s: %w", coll.Name, err)
+			}
+			layoutTree(layout, workspace.Slugify(coll.Name), trees[i])
+			ids = append(ids, treeIDs(trees[i])...)
+		}
+
+		var mu sync.Mutex
+		texts := make(map[string]string, len(ids))
+		bulkErr := runBulk(cmd, "export", ids, func(id string) (statusEntry, error) {
+			doc, err := client.GetDocument(id)
+			if err != nil {
+				return statusEntry{}, fmt.Errorf("fetching document: %w", err)
+			}
+			// Attachment links end up relative to the site root
+			text, err := downloadAttachments(client, doc.Text, exportOut)
+			if err != nil {
+				return statusEntry{}, err
+			}
+
+			mu.Lock()
+			texts[id] = text
+			mu.Unlock()
+			return statusEntry{ID: id, Title: doc.Title, Action: "exported", Path: filepath.Join(exportOut, pagePath(layout[id]))}, nil
+		})
+
+		for i, coll := range collections {
+			s.Sections = append(s.Sections, &site.Section{
+				Name:  coll.Name,
+				Pages: sitePages(trees[i], layout, texts),
+			})
+		}
+		if err := s.Build(exportOut); err != nil {
+			return fmt.Errorf("building site: %w", err)
+		}
+		logger.Info("exported site", "dir", exportOut, "documents", len(texts))
+		return bulkErr
+	},
+}
+
+// downloadArchive saves the archive of a completed export to path. The
+// file only appears once the download has finished.
+func downloadArchive(client api.Client, id string, path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*.zip")
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := client.DownloadFileOperation(id, tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("downloading export: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing archive: %w", err)
+	}
+	return nil
+}
+
+// treeIDs returns the IDs of every document in a navigation tree
+func treeIDs(nodes []api.NavigationNode) []string {
+	var ids []string
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+		ids = append(ids, treeIDs(n.Children)...)
+	}
+	return ids
+}
+
+// sitePages turns a navigation tree into site pages. Documents that failed
+// to export keep their place in the tree with an empty page.
+func sitePages(nodes []api.NavigationNode, layout map[string]string, texts map[string]string) []*site.Page {
+	var pages []*site.Page
+	for _, n := range nodes {
+		urlID, _ := api.LinkedDocumentID(n.URL)
+		pages = append(pages, &site.Page{
+			ID:       n.ID,
+			URLID:    urlID,
+			Title:    n.Title,
+			Path:     pagePath(layout[n.ID]),
+			Markdown: texts[n.ID],
+			Children: sitePages(n.Children, layout, texts),
+		})
+	}
+	return pages
+}
+
+// pagePath turns a path from layoutTree into the path of its page
+func pagePath(mdPath string) string {
+	return strings.TrimSuffix(mdPath, workspace.Ext) + ".html"
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportCollection, "collection", "", "collection to export by ID, url-id or name (default the whole workspace)")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "markdown", "archive format: markdown, json or html")
+	exportCmd.Flags().StringVarP(&exportFile, "out", "o", "", "path to save the archive to (default <collection>.zip)")
+	exportCmd.Flags().BoolVar(&exportUnpack, "unpack", false, "extract the archive into a directory named after it")
+
+	exportSiteCmd.Flags().StringVarP(&exportOut, "out", "o", "site", "directory to write the site to")
+	exportSiteCmd.Flags().StringVar(&exportSiteTitle, "title", "", "site title (default the collection name, or Outline)")
+	exportSiteCmd.Flags().IntVar(&bulkConcurrency, "concurrency", 4, "number of documents to fetch at once")
+
+	exportCmd.AddCommand(exportSiteCmd)
+	RootCmd.AddCommand(exportCmd)
+}
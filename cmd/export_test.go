@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"outline-cli/api"
+)
+
+func TestExportSite(t *testing.T) {
+	srv := useFakeServer(t)
+
+	coll := srv.AddCollection("Runbooks")
+	srv.AddCollection("Other")
+	parent := srv.AddDocument(api.Document{Title: "Databases", Text: "Overview", CollectionID: coll.ID})
+	att := srv.AddAttachment("chart.png", "image/png", []byte("chart"), "")
+	srv.AddDocument(api.Document{
+		Title:            "Failover",
+		Text:             "Back to [databases](/doc/databases-" + parent.URLID + ")\n\n![chart](" + att.URL + ")\n",
+		CollectionID:     coll.ID,
+		ParentDocumentID: parent.ID,
+	})
+
+	out := t.TempDir()
+	RootCmd.SetArgs([]string{"export", "site", "Runbooks", "--out", out})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	page, err := os.ReadFile(filepath.Join(out, "Runbooks", "Databases", "Failover.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `href="../../Runbooks/Databases.html"`) {
+		t.Errorf("expected a relative link to the parent page, got:\n%s", page)
+	}
+	if !strings.Contains(string(page), `src="../../assets/`+att.ID+`.png"`) {
+		t.Errorf("expected the bundled attachment, got:\n%s", page)
+	}
+	if _, err := os.Stat(filepath.Join(out, "assets", att.ID+".png")); err != nil {
+		t.Errorf("expected attachment in assets: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "Other")); !os.IsNotExist(err) {
+		t.Error("exported a collection that wasn't selected")
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	srv := useFakeServer(t)
+	fileOperationPoll = time.Millisecond
+	defer func() { fileOperationPoll = 2 * time.Second }()
+
+	coll := srv.AddCollection("Runbooks")
+	parent := srv.AddDocument(api.Document{Title: "Databases", Text: "Overview", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{Title: "Failover", Text: "Promote the replica.", CollectionID: coll.ID, ParentDocumentID: parent.ID})
+
+	out := filepath.Join(t.TempDir(), "runbooks.zip")
+	RootCmd.SetArgs([]string{"export", "--collection", "Runbooks", "--format", "markdown", "--out", out, "--unpack"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(out); err != nil {
+		t.Fatalf("expected the archive: %v", err)
+	}
+	child, err := os.ReadFile(filepath.Join(strings.TrimSuffix(out, ".zip"), "Runbooks", "Databases", "Failover.md"))
+	if err != nil {
+		t.Fatalf("expected the unpacked child document: %v", err)
+	}
+	if !strings.Contains(string(child), "Promote the replica.") {
+		t.Errorf("unexpected document content %q", child)
+	}
+
+	calls := strings.Join(srv.Calls(), " ")
+	if !strings.Contains(calls, "collections.export fileOperations.info fileOperations.info fileOperations.redirect") {
+		t.Errorf("expected the export to be polled until complete, got calls %s", calls)
+	}
+}
+
+func TestExportUnpackKeepsArchiveAndExistingFiles(t *testing.T) {
+	srv := useFakeServer(t)
+	fileOperationPoll = time.Millisecond
+	defer func() { fileOperationPoll = 2 * time.Second }()
+
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{Title: "Failover", Text: "Promote the replica.", CollectionID: coll.ID})
+
+	// Without an extension the archive and the directory would collide
+	out := filepath.Join(t.TempDir(), "runbooks")
+	args := []string{"export", "--collection", "Runbooks", "--format", "markdown", "--out", out, "--unpack"}
+	RootCmd.SetArgs(args)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("expected the archive kept at %s: %v", out, err)
+	}
+	zr.Close()
+	if _, err := os.Stat(filepath.Join(out+".d", "Runbooks", "Failover.md")); err != nil {
+		t.Errorf("expected the archive unpacked into %s.d: %v", out, err)
+	}
+
+	resetCommands()
+	RootCmd.SetArgs(args)
+	if err := RootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("expected a second unpack into the same directory to be refused, got %v", err)
+	}
+}
+
+func TestUnpackArchiveRefusesEscapingEntries(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	if _, err := zw.Create("../escaped.md"); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+	f.Close()
+
+	dir := filepath.Join(t.TempDir(), "out")
+	if err := unpackArchive(archive, dir); err == nil {
+		t.Fatal("expected an error for an entry outside the directory")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escaped.md")); !os.IsNotExist(err) {
+		t.Error("an entry was written outside the target directory")
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"outline-cli/api"
+
+	"github.com/spf13/cobra"
+)
+
+// fileOperationPoll is how often a running export or import is checked
+var fileOperationPoll = 2 * time.Second
+
+// archiveFormats maps --format values to Outline's archive formats
+var archiveFormats = map[string]string{
+	"markdown": api.FormatMarkdown,
+	"json":     api.FormatJSON,
+	"html":     api.FormatHTML,
+}
+
+// waitForFileOperation polls op until Outline finishes it. State changes
+// are shown on a status line on terminals and logged otherwise.
+func waitForFileOperation(cmd *cobra.Command, client api.Client, op *api.FileOperation, verb string) (*api.FileOperation, error) {
+	w := cmd.ErrOrStderr()
+	if !isTerminal(w) {
+		w = io.Discard
+	}
+	defer fmt.Fprint(w, "\r\033[K")
+
+	start := time.Now()
+	state := ""
+	for {
+		if op.State != state {
+			state = op.State
+			logger.Info(verb+" "+state, "id", op.ID)
+		}
+		fmt.Fprintf(w, "\r\033[K%s: %s (%s)", verb, state, time.Since(start).Round(time.Second))
+
+		switch op.State {
+		case api.FileOperationComplete:
+			return op, nil
+		case api.FileOperationError:
+			return nil, fmt.Errorf("%s failed: %s", verb, op.Error)
+		case api.FileOperationExpired:
+			return nil, fmt.Errorf("%s expired", verb)
+		}
+
+		time.Sleep(fileOperationPoll)
+		next, err := client.GetFileOperation(op.ID)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s: %w", verb, err)
+		}
+		op = next
+	}
+}
+
+// unpackDir is the directory --unpack extracts the archive at out into: out
+// without its extension, or out with ".d" appended when it has none
+func unpackDir(out string) string {
+	base := filepath.Base(out)
+	if ext := filepath.Ext(base); ext != "" && ext != base {
+		return strings.TrimSuffix(out, ext)
+	}
+	return out + ".d"
+}
+
+// checkUnpackDir refuses to extract into a directory that already has
+// files, which the archive would overwrite
+func checkUnpackDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("checking %s: %w", dir, err)
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("%s is not empty; remove it or choose another --out", dir)
+	}
+	return nil
+}
+
+// unpackArchive extracts a zip into dir. Entries that would land outside
+// dir are refused.
+func unpackArchive(archive string, dir string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		name := filepath.FromSlash(f.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("archive entry %q is outside the target directory", f.Name)
+		}
+		target := filepath.Join(dir, name)
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("creating directory: %w", err)
+			}
+			continue
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return fmt.Errorf("writing %s: %w", target, err)
+	}
+	return out.Close()
+}
+
+// zipDirectory archives the files below dir, skipping hidden files such as
+// .git and sync's state
+func zipDirectory(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && d.Name()[0] == '.' {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fw, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archiving %s: %w", dir, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("archiving %s: %w", dir, err)
+	}
+	return buf.Bytes(), nil
+}
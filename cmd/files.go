@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// pulledFile records, per directory, the hash of each file as it was last
+// written by pull or sent by push, so pull can tell a stale copy apart from
+// unsaved local edits
+const pulledFile = ".outline-pulled.json"
+
+var pulledMu sync.Mutex
+
+// errLocalChanges is returned when pull would discard local edits
+var errLocalChanges = errors.New("file has local changes")
+
+// checkOverwrite returns errLocalChanges when filename exists with content
+// that is neither text nor the version last pulled or pushed
+func checkOverwrite(filename string, text string) error {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if string(data) == text {
+		return nil
+	}
+
+	pulledMu.Lock()
+	hashes, err := loadPulled(filepath.Dir(filename))
+	pulledMu.Unlock()
+	if err != nil {
+		return err
+	}
+	if hash, ok := hashes[filepath.Base(filename)]; ok && hash == contentHash(data) {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", filename, errLocalChanges)
+}
+
+// recordPulled remembers data as the version of filename that matches Outline
+func recordPulled(filename string, data []byte) error {
+	pulledMu.Lock()
+	defer pulledMu.Unlock()
+
+	dir := filepath.Dir(filename)
+	hashes, err := loadPulled(dir)
+	if err != nil {
+		return err
+	}
+	hashes[filepath.Base(filename)] = contentHash(data)
+
+	encoded, err := json.MarshalIndent(hashes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", pulledFile, err)
+	}
+	return writeFileAtomic(filepath.Join(dir, pulledFile), encoded, 0644)
+}
+
+func loadPulled(dir string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(dir, pulledFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return hashes, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", pulledFile, err)
+	}
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", pulledFile, err)
+	}
+	return hashes, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and
+// renames it into place, so readers never see a partial file. An existing
+// file keeps its mode; a new one gets perm.
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(filename); err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"outline-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+var importFormat string
+
+var importCmd = &cobra.Command{
+	Use:   "import <zip|dir>",
+	Short: "Import an archive of collections into Outline",
+	Long: `Import an archive of collections into Outline.
+
+The archive is uploaded and handed to Outline, which creates a new
+collection for each collection in it; progress is reported until the import
+completes. Archives are laid out like Outline's own exports, so the output
+of export can be imported as is. A directory is zipped before uploading,
+leaving out hidden files.
+
+Formats are markdown and json.`,
+	Example: `  outline import Runbooks.zip
+  outline import ./exported --format markdown`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, ok := archiveFormats[importFormat]
+		if !ok || importFormat == "html" {
+			return fmt.Errorf("--format must be markdown or json, not %q", importFormat)
+		}
+
+		source := args[0]
+		info, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", source, err)
+		}
+		var data []byte
+		name := filepath.Base(source)
+		if info.IsDir() {
+			data, err = zipDirectory(source)
+			name = filepath.Base(filepath.Clean(source)) + ".zip"
+		} else {
+			data, err = os.ReadFile(source)
+		}
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		logger.Info("uploading archive", "name", name, "bytes", len(data))
+		if isTerminal(cmd.ErrOrStderr()) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "\r\033[Kimport: uploading %s (%d KB)", name, (len(data)+1023)/1024)
+		}
+		att, err := client.CreateImportAttachment(name, data)
+		if err != nil {
+			return fmt.Errorf("uploading archive: %w", err)
+		}
+		op, err := client.ImportCollection(att.ID, format)
+		if err != nil {
+			return fmt.Errorf("starting import: %w", err)
+		}
+		entry := statusEntry{ID: op.ID, Title: name, Action: "imported", Path: source}
+		if dryRun {
+			return printer.Print(dryRunStatus(entry), "id", "action", "path", "message")
+		}
+
+		if _, err := waitForFileOperation(cmd, client, op, "import"); err != nil {
+			return err
+		}
+		return printer.Print(entry, "id", "action", "path", "message")
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importFormat, "format", "markdown", "archive format: markdown or json")
+
+	RootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportDirectory(t *testing.T) {
+	srv := useFakeServer(t)
+	fileOperationPoll = time.Millisecond
+	defer func() { fileOperationPoll = 2 * time.Second }()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"Handbook/Onboarding.md":        "# Onboarding\n\nWelcome aboard.",
+		"Handbook/Onboarding/Laptop.md": "Order a laptop.",
+		".git/config":                   "ignored",
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	RootCmd.SetArgs([]string{"import", dir})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	docs := srv.Documents()
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 imported documents, got %d", len(docs))
+	}
+	parent, child := docs[0], docs[1]
+	if parent.Title != "Onboarding" || parent.Text != "Welcome aboard." {
+		t.Errorf("unexpected parent %q: %q", parent.Title, parent.Text)
+	}
+	if child.Title != "Laptop" || child.ParentDocumentID != parent.ID {
+		t.Errorf("expected Laptop nested below Onboarding, got %+v", child)
+	}
+
+	calls := strings.Join(srv.Calls(), " ")
+	if !strings.Contains(calls, "attachments.create files.create collections.import fileOperations.info") {
+		t.Errorf("expected the archive to be uploaded and imported, got calls %s", calls)
+	}
+}
+
+func TestImportReportsFailure(t *testing.T) {
+	useFakeServer(t)
+	fileOperationPoll = time.Millisecond
+	defer func() { fileOperationPoll = 2 * time.Second }()
+
+	archive := filepath.Join(t.TempDir(), "broken.zip")
+	if err := os.WriteFile(archive, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	RootCmd.SetArgs([]string{"import", archive})
+	err := RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "import failed: invalid zip file") {
+		t.Fatalf("expected the import error from Outline, got %v", err)
+	}
+}
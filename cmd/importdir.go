@@ -0,0 +1,416 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"outline-cli/api"
+	"outline-cli/assets"
+	"outline-cli/config"
+	"outline-cli/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+// importStateFile records, in the imported directory, the document created
+// for each file so an interrupted import-dir can pick up where it stopped
+const importStateFile = ".outline-import.json"
+
+// folderBodies are the files, in order of preference, whose text becomes
+// the body of their folder's document
+var folderBodies = []string{"readme.md", "index.md"}
+
+var importDirCollection string
+
+var importDirCmd = &cobra.Command{
+	Use:   "import-dir <dir>",
+	Short: "Import a directory of Markdown files as a document hierarchy",
+	Long: `Import a directory of Markdown files into a collection as a document hierarchy.
+
+Every Markdown file becomes a document and every folder a parent document
+holding what is inside it, with the folder's README.md or index.md, if any,
+as its text. A leading "# Heading" becomes the title; otherwise the file or
+folder name is used. Hidden files and folders without Markdown are skipped.
+
+Images and other files linked with relative paths inside the directory are
+uploaded as attachments. Relative links to other imported files or folders
+are rewritten to their Outline URLs once every document exists.
+
+Progress is recorded in ` + importStateFile + ` in the directory. Running the
+command again after an interruption resumes the import without creating
+documents twice.`,
+	Example: `  outline import-dir ./docs --collection Engineering`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if importDirCollection == "" {
+			return fmt.Errorf("--collection is required")
+		}
+
+		root, err := workspace.NewRoot(args[0])
+		if err != nil {
+			return err
+		}
+		nodes, err := scanImportDir(root.Dir(), "")
+		if err != nil {
+			return err
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("no Markdown files found in %s", args[0])
+		}
+
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		collections, err := client.ListCollections()
+		if err != nil {
+			return fmt.Errorf("listing collections: %w", err)
+		}
+		selected, err := selectCollections(collections, []string{importDirCollection})
+		if err != nil {
+			return err
+		}
+
+		state, err := loadImportState(root.Dir())
+		if err != nil {
+			return err
+		}
+		if state.CollectionID != "" && state.CollectionID != selected[0].ID {
+			return fmt.Errorf("%s was imported into another collection; remove %s to import it again", args[0], importStateFile)
+		}
+		state.CollectionID = selected[0].ID
+
+		imp := &dirImporter{client: client, root: root, state: state, aliases: make(map[string]string)}
+		imp.index(nodes)
+
+		progress := newProgressBar(cmd.ErrOrStderr(), "import", imp.count(nodes))
+		// Every document is created before links are rewritten, so links
+		// to documents further down the tree can be resolved too
+		err = imp.create(nodes, "", progress)
+		if err == nil && !dryRun {
+			err = imp.link(nodes)
+		}
+		progress.finish()
+
+		if printErr := printer.Print(imp.results, "id", "action", "path", "message"); printErr != nil {
+			return printErr
+		}
+		return err
+	},
+}
+
+// importNode is a document to create from a file or folder
+type importNode struct {
+	// Path identifies the node relative to the imported directory: the
+	// slash-separated path of its file, or of its folder ending in "/"
+	Path string
+	// File is the Markdown file holding the text, empty for a folder
+	// without one
+	File     string
+	Title    string
+	Children []*importNode
+}
+
+// importState is the persisted progress of an import-dir
+type importState struct {
+	CollectionID string                       `json:"collectionId"`
+	Documents    map[string]*importedDocument `json:"documents"`
+}
+
+type importedDocument struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+	// Linked is set once relative links in the document have been rewritten
+	Linked bool `json:"linked"`
+}
+
+// dirImporter creates the documents of an import-dir
+type dirImporter struct {
+	client api.Client
+	root   *workspace.Root
+	state  *importState
+	// aliases maps every path a link may use for a node, such as a
+	// folder's README.md, to the node's Path
+	aliases map[string]string
+	results []statusEntry
+}
+
+// scanImportDir builds the nodes for the Markdown files and folders in rel
+func scanImportDir(root string, rel string) ([]*importNode, error) {
+	entries, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(rel)))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", rel, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var nodes []*importNode
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !e.IsDir() {
+			if isMarkdown(name) && (rel == "" || folderBody(entries) != name) {
+				nodes = append(nodes, &importNode{Path: rel + name, File: rel + name, Title: strings.TrimSuffix(name, path.Ext(name))})
+			}
+			continue
+		}
+
+		dir := rel + name + "/"
+		sub, err := os.ReadDir(filepath.Join(root, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", dir, err)
+		}
+		children, err := scanImportDir(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		body := folderBody(sub)
+		if body == "" && len(children) == 0 {
+			continue
+		}
+		node := &importNode{Path: dir, Title: name, Children: children}
+		if body != "" {
+			node.File = dir + body
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// folderBody returns the name of the file among entries that holds the
+// text of their folder, if any
+func folderBody(entries []os.DirEntry) string {
+	for _, want := range folderBodies {
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(e.Name(), want) {
+				return e.Name()
+			}
+		}
+	}
+	return ""
+}
+
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// splitTitle takes a leading "# Heading" out of text as its title
+func splitTitle(text string) (string, string) {
+	first, rest, _ := strings.Cut(strings.TrimLeft(text, "\r\n"), "\n")
+	title, ok := strings.CutPrefix(strings.TrimSpace(first), "# ")
+	if !ok {
+		return "", text
+	}
+	return strings.TrimSpace(title), strings.TrimLeft(rest, "\r\n")
+}
+
+// index records the paths links may use to refer to each node
+func (imp *dirImporter) index(nodes []*importNode) {
+	for _, n := range nodes {
+		imp.aliases[n.Path] = n.Path
+		imp.aliases[strings.TrimSuffix(n.Path, "/")] = n.Path
+		if n.File != "" {
+			imp.aliases[n.File] = n.Path
+		}
+		imp.index(n.Children)
+	}
+}
+
+func (imp *dirImporter) count(nodes []*importNode) int {
+	total := len(nodes)
+	for _, n := range nodes {
+		total += imp.count(n.Children)
+	}
+	return total
+}
+
+// create creates the documents for nodes below parentID, skipping those
+// created by an earlier run
+func (imp *dirImporter) create(nodes []*importNode, parentID string, progress *progressBar) error {
+	for _, n := range nodes {
+		rec, done := imp.state.Documents[n.Path]
+		if done {
+			imp.results = append(imp.results, statusEntry{ID: rec.ID, Title: n.Title, Action: "skipped", Path: n.Path, Message: "imported earlier"})
+		} else {
+			var err error
+			rec, err = imp.createDocument(n, parentID)
+			if err != nil {
+				imp.results = append(imp.results, statusEntry{Title: n.Title, Action: "failed", Path: n.Path, Message: err.Error()})
+				return fmt.Errorf("importing %s: %w", n.Path, err)
+			}
+			imp.results = append(imp.results, dryRunStatus(statusEntry{ID: rec.ID, Title: n.Title, Action: "created", Path: n.Path}))
+		}
+		progress.increment()
+
+		if err := imp.create(n.Children, rec.ID, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (imp *dirImporter) createDocument(n *importNode, parentID string) (*importedDocument, error) {
+	title, text := n.Title, ""
+	if n.File != "" {
+		filename, err := imp.root.Resolve(n.File)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", n.File, err)
+		}
+		heading, body := splitTitle(string(data))
+		if heading != "" {
+			title = heading
+		}
+		text = body
+	}
+
+	text, linked := imp.rewriteLinks(text, n.File)
+	doc, err := imp.client.CreateDocument(title, text, imp.state.CollectionID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	// Attachments belong to a document, so linked files are uploaded once
+	// it exists
+	if n.File != "" {
+		if err := imp.attach(doc.ID, text, n.File); err != nil {
+			// Leave nothing behind, so the next run creates it again
+			if err := imp.client.DeleteDocument(doc.ID); err != nil {
+				logger.Warn("could not delete partly imported document", "id", doc.ID, "error", err)
+			}
+			return nil, err
+		}
+	}
+	rec := &importedDocument{ID: doc.ID, URL: "/doc/" + doc.URLID, Linked: linked}
+	if dryRun {
+		return rec, nil
+	}
+	imp.state.Documents[n.Path] = rec
+	return rec, imp.state.save(imp.root.Dir())
+}
+
+// attach uploads the files linked from the text of file as attachments of
+// the document with the given ID and points the links at them
+func (imp *dirImporter) attach(docID string, text string, file string) error {
+	uploaded, err := uploadLinkedFiles(imp.client, text, imp.root, path.Dir(file), docID)
+	if err != nil {
+		return err
+	}
+	if uploaded == text {
+		return nil
+	}
+	if err := imp.client.UpdateDocument(docID, uploaded); err != nil {
+		return fmt.Errorf("linking attachments: %w", err)
+	}
+	return nil
+}
+
+// link rewrites the links created documents couldn't resolve at the time,
+// which point to documents created after them
+func (imp *dirImporter) link(nodes []*importNode) error {
+	for _, n := range nodes {
+		rec := imp.state.Documents[n.Path]
+		if !rec.Linked {
+			doc, err := imp.client.GetDocument(rec.ID)
+			if err != nil {
+				return fmt.Errorf("fetching %s: %w", n.Path, err)
+			}
+			if text, _ := imp.rewriteLinks(doc.Text, n.File); text != doc.Text {
+				if err := imp.client.UpdateDocument(rec.ID, text); err != nil {
+					return fmt.Errorf("rewriting links in %s: %w", n.Path, err)
+				}
+			}
+			rec.Linked = true
+			if err := imp.state.save(imp.root.Dir()); err != nil {
+				return err
+			}
+		}
+		if err := imp.link(n.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteLinks points relative links in the text of file at the documents
+// already created for their targets. It reports whether no link was left
+// that a later document could resolve.
+func (imp *dirImporter) rewriteLinks(text string, file string) (string, bool) {
+	resolved := true
+	for _, target := range assets.DocumentLinks(text) {
+		rawPath, fragment, _ := strings.Cut(target, "#")
+		p, err := url.PathUnescape(rawPath)
+		if err != nil {
+			p = rawPath
+		}
+		joined := path.Join(path.Dir(file), p)
+		if strings.HasSuffix(p, "/") {
+			joined += "/"
+		}
+
+		node, ok := imp.aliases[joined]
+		if !ok {
+			logger.Warn("link target is not being imported, leaving link unchanged", "file", file, "link", target)
+			continue
+		}
+		rec, ok := imp.state.Documents[node]
+		if !ok {
+			resolved = false
+			continue
+		}
+		link := rec.URL
+		if fragment != "" {
+			link += "#" + fragment
+		}
+		text = assets.ReplaceLink(text, target, link)
+	}
+	return text, resolved
+}
+
+func loadImportState(dir string) (*importState, error) {
+	state := &importState{Documents: make(map[string]*importedDocument)}
+	data, err := os.ReadFile(filepath.Join(dir, importStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", importStateFile, err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", importStateFile, err)
+	}
+	if state.Documents == nil {
+		state.Documents = make(map[string]*importedDocument)
+	}
+	return state, nil
+}
+
+func (s *importState) save(dir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", importStateFile, err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, importStateFile), data, 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", importStateFile, err)
+	}
+	return nil
+}
+
+func init() {
+	importDirCmd.Flags().StringVar(&importDirCollection, "collection", "", "collection to import into by ID, url-id or name")
+
+	RootCmd.AddCommand(importDirCmd)
+}
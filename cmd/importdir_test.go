@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/outlinetest"
+	"outline-cli/workspace"
+)
+
+func TestImportDirResumes(t *testing.T) {
+	srv := useFakeServer(t)
+
+	coll := srv.AddCollection("Engineering")
+	dir := t.TempDir()
+	files := map[string]string{
+		"README.md":       "# Welcome\n\nSee [setup](guides/setup.md#install) and [the guides](guides/).",
+		"guides/index.md": "# Guides\n\nStart with [setup](./setup.md).",
+		"guides/setup.md": "Back to [home](../README.md).\n\n![diagram](../images/arch.png)",
+		"images/arch.png": "png",
+		".drafts/wip.md":  "not imported",
+	}
+	for name, text := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Interrupt the import once every document exists but before the
+	// links to later documents are rewritten. Failed reads are retried
+	// unless the error is final, so the fault is a 403.
+	srv.Fail("documents.info", outlinetest.Fault{Status: http.StatusForbidden, Times: 1})
+	RootCmd.SetArgs([]string{"import-dir", dir, "--collection", "Engineering"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Fatal("expected the interrupted import to fail")
+	}
+
+	resetCommands()
+	RootCmd.SetArgs([]string{"import-dir", dir, "--collection", "Engineering"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error resuming: %v", err)
+	}
+
+	docs := make(map[string]api.Document)
+	for _, doc := range srv.Documents() {
+		docs[doc.Title] = doc
+	}
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents without duplicates, got %d", len(srv.Documents()))
+	}
+	welcome, guides, setup := docs["Welcome"], docs["Guides"], docs["setup"]
+	if welcome.CollectionID != coll.ID || welcome.ParentDocumentID != "" {
+		t.Errorf("expected Welcome at the top of the collection, got %+v", welcome)
+	}
+	if setup.ParentDocumentID != guides.ID {
+		t.Errorf("expected setup nested below the Guides folder, got parent %q", setup.ParentDocumentID)
+	}
+
+	want := "See [setup](/doc/" + setup.URLID + "#install) and [the guides](/doc/" + guides.URLID + ")."
+	if welcome.Text != want {
+		t.Errorf("expected links rewritten on resume:\n got %q\nwant %q", welcome.Text, want)
+	}
+	if !strings.Contains(guides.Text, "(/doc/"+setup.URLID+")") {
+		t.Errorf("expected the folder body to link to setup, got %q", guides.Text)
+	}
+	if !strings.Contains(setup.Text, "[home](/doc/"+welcome.URLID+")") {
+		t.Errorf("expected the link home, got %q", setup.Text)
+	}
+	ids := strings.Split(setup.Text, "attachments.redirect?id=")
+	if len(ids) != 2 {
+		t.Fatalf("expected one attachment link, got %q", setup.Text)
+	}
+	if att, ok := srv.Attachment(strings.TrimSuffix(ids[1], ")")); !ok || att.DocumentID != setup.ID {
+		t.Errorf("expected the image uploaded as an attachment of setup, got %+v", att)
+	}
+}
+
+func TestImportDirStaysInsideRoot(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddCollection("Engineering")
+
+	dir, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "secret.md")
+	if err := os.WriteFile(secret, []byte("Not for import"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "notes.md")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	RootCmd.SetArgs([]string{"import-dir", dir, "--collection", "Engineering"})
+	if err := RootCmd.Execute(); !errors.Is(err, workspace.ErrOutsideRoot) {
+		t.Errorf("expected import-dir to refuse the symlinked file, got %v", err)
+	}
+	if docs := srv.Documents(); len(docs) != 0 {
+		t.Errorf("expected nothing imported, got %+v", docs)
+	}
+}
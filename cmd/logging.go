@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"outline-cli/api"
+)
+
+// logger receives diagnostics for the current invocation. It discards
+// everything until the root command's pre-run hook configures it.
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// apiMetrics counts the API calls of the current invocation
+var apiMetrics = api.NewMetrics()
+
+// sensitiveLogKeys lists attribute keys whose values are always redacted
+var sensitiveLogKeys = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"authorization": true,
+	"token":         true,
+	"password":      true,
+}
+
+// newLogger builds a logger writing to w at the given level and format
+func newLogger(w io.Writer, level string, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: must be one of debug, info, warn, error", level)
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redactAttr,
+	}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: must be text or json", format)
+	}
+}
+
+// redactAttr masks values of attributes that look like credentials
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveLogKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, api.RedactSecret(a.Value.String()))
+	}
+	return a
+}
+
+// logMetrics summarizes the invocation's API calls at info level
+func logMetrics() {
+	for _, s := range apiMetrics.Snapshot() {
+		logger.Info("api calls", "method", s.Method, "calls", s.Calls, "errors", s.Errors, "total", s.Total)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+)
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := newLogger(&buf, "debug", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer super-secret-token")
+	l.Debug("api request", "headers", api.RedactHeaders(headers), "api_key", "super-secret-token")
+
+	if strings.Contains(buf.String(), "super-secret-token") {
+		t.Errorf("log output leaked secret: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Bearer [REDACTED]") {
+		t.Errorf("expected redacted authorization header, got: %s", buf.String())
+	}
+}
+
+func TestLoggerRejectsInvalidOptions(t *testing.T) {
+	if _, err := newLogger(&bytes.Buffer{}, "chatty", "text"); err == nil {
+		t.Error("expected error for invalid log level")
+	}
+	if _, err := newLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("expected error for invalid log format")
+	}
+}
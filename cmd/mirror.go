@@ -0,0 +1,556 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"outline-cli/api"
+	"outline-cli/assets"
+	"outline-cli/config"
+
+	"github.com/spf13/cobra"
+)
+
+var mirrorFrom string
+var mirrorTo string
+var mirrorCollections []string
+var mirrorMapFile string
+
+var mirrorCmd = &cobra.Command{
+	Use:   "mirror",
+	Short: "Copy collections from one Outline instance to another",
+	Long: `Copy collections, their document hierarchy and attachments from one Outline
+instance to another, such as when migrating or keeping a staging mirror.
+
+Both instances are named by profiles in config.json:
+
+  {"profiles": {"old": {"outline_url": "https://...", "api_key": "..."},
+                "new": {"outline_url": "https://...", "api_key": "..."}}}
+
+Collections are created on the target with their name, description, color,
+icon and default permission, and documents with their title, icon and emoji
+under the same parent. Memberships, shares, comments and revision history
+are not copied. Attachments are copied
+once each. Links to mirrored documents and attachment URLs are rewritten to
+point at the copies; links to documents that aren't mirrored point back at
+the source.
+
+The ID of every copy is kept in a mapping file, ~/.outline-cli/mirror-
+<from>-<to>.json unless --map is given, so running mirror again updates the
+copies rather than duplicating them: renamed, moved and edited documents are
+brought in line with the source. Copies deleted on the target are created
+again when their source changes. Documents deleted from the source are left
+on the target.
+
+Without --collection every collection is mirrored.`,
+	Example: `  outline mirror --from old --to new
+  outline mirror --from prod --to staging --collection Runbooks`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if mirrorFrom == "" || mirrorTo == "" {
+			return fmt.Errorf("--from and --to are required")
+		}
+		if mirrorFrom == mirrorTo {
+			return fmt.Errorf("--from and --to must be different profiles")
+		}
+		sourceCfg, err := config.LoadProfile(mirrorFrom)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+		targetCfg, err := config.LoadProfile(mirrorTo)
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		mapFile := mirrorMapFile
+		if mapFile == "" {
+			dir, err := config.Dir()
+			if err != nil {
+				return err
+			}
+			mapFile = filepath.Join(dir, "mirror-"+mirrorFrom+"-"+mirrorTo+".json")
+		}
+		state, err := loadMirrorState(mapFile)
+		if err != nil {
+			return err
+		}
+		sourceURL, targetURL := strings.TrimRight(sourceCfg.OutlineURL, "/"), strings.TrimRight(targetCfg.OutlineURL, "/")
+		if state.Source != "" && (state.Source != sourceURL || state.Target != targetURL) {
+			return fmt.Errorf("%s maps %s to %s, not %s to %s", mapFile, state.Source, state.Target, sourceURL, targetURL)
+		}
+		state.Source, state.Target = sourceURL, targetURL
+
+		m := &mirrorer{
+			source:    newClient(sourceCfg),
+			target:    newClient(targetCfg),
+			state:     state,
+			file:      mapFile,
+			sourceIDs: make(map[string]string),
+			texts:     make(map[string]string),
+			results:   make(map[string]int),
+		}
+
+		collections, err := m.source.ListCollections()
+		if err != nil {
+			return fmt.Errorf("listing source collections: %w", err)
+		}
+		collections, err = selectCollections(collections, mirrorCollections)
+		if err != nil {
+			return err
+		}
+		targets, err := m.target.ListCollections()
+		if err != nil {
+			return fmt.Errorf("listing target collections: %w", err)
+		}
+
+		trees := make([][]api.NavigationNode, len(collections))
+		total := 0
+		for i, coll := range collections {
+			trees[i], err = m.source.CollectionDocuments(coll.ID)
+			if err != nil {
+				return fmt.Errorf("fetching document tree of %s: %w", coll.Name, err)
+			}
+			m.index(trees[i])
+			total += len(treeIDs(trees[i]))
+		}
+
+		progress := newProgressBar(cmd.ErrOrStderr(), "mirror", total)
+		err = m.mirror(collections, targets, trees, progress)
+		progress.finish()
+
+		if printErr := printer.Print(m.entries, "id", "title", "action", "message"); printErr != nil {
+			return printErr
+		}
+		return err
+	},
+}
+
+// mirrorState maps source IDs to the IDs of their copies on the target
+type mirrorState struct {
+	Source      string                       `json:"source"`
+	Target      string                       `json:"target"`
+	Collections map[string]string            `json:"collections"`
+	Documents   map[string]*mirroredDocument `json:"documents"`
+	Attachments map[string]string            `json:"attachments"`
+}
+
+// mirroredDocument is the copy of a source document as last written
+type mirroredDocument struct {
+	ID           string `json:"id"`
+	URLID        string `json:"urlId"`
+	CollectionID string `json:"collectionId"`
+	ParentID     string `json:"parentId,omitempty"`
+	Title        string `json:"title"`
+	Icon         string `json:"icon,omitempty"`
+	Emoji        string `json:"emoji,omitempty"`
+	// Hash is the SHA-256 of the text last written to the copy
+	Hash string `json:"hash"`
+	// Pending is set while the copy links to documents not copied yet
+	Pending bool `json:"pending,omitempty"`
+}
+
+// mirrorer copies documents from source to target
+type mirrorer struct {
+	source api.Client
+	target api.Client
+	state  *mirrorState
+	file   string
+	// sourceIDs maps the IDs and url-ids of mirrored source documents to
+	// their IDs
+	sourceIDs map[string]string
+	// texts caches source document text for the second pass
+	texts   map[string]string
+	entries []statusEntry
+	// results indexes entries by source document ID
+	results map[string]int
+}
+
+func (m *mirrorer) index(nodes []api.NavigationNode) {
+	for _, n := range nodes {
+		m.sourceIDs[n.ID] = n.ID
+		if urlID, ok := api.LinkedDocumentID(n.URL); ok {
+			m.sourceIDs[urlID] = n.ID
+		}
+		m.index(n.Children)
+	}
+}
+
+func (m *mirrorer) mirror(collections []api.Collection, targets []api.Collection, trees [][]api.NavigationNode, progress *progressBar) error {
+	for i, coll := range collections {
+		collectionID, err := m.collection(coll, targets)
+		if err != nil {
+			return err
+		}
+		if err := m.documents(trees[i], collectionID, "", progress); err != nil {
+			return err
+		}
+	}
+	if dryRun {
+		return nil
+	}
+	// Documents copied before the documents they link to are fixed up now
+	// that every copy exists
+	for _, tree := range trees {
+		if err := m.relink(tree); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collection returns the ID of the copy of coll, creating it if needed
+func (m *mirrorer) collection(coll api.Collection, targets []api.Collection) (string, error) {
+	id, mapped := m.state.Collections[coll.ID]
+	var existing *api.Collection
+	for i := range targets {
+		if mapped && targets[i].ID == id {
+			existing = &targets[i]
+		}
+	}
+
+	if existing == nil {
+		var response struct {
+			Data api.Collection `json:"data"`
+		}
+		if err := m.target.Call("collections.create", collectionPayload(coll), &response); err != nil {
+			return "", fmt.Errorf("creating collection %s: %w", coll.Name, err)
+		}
+		m.entries = append(m.entries, dryRunStatus(statusEntry{ID: response.Data.ID, Title: coll.Name, Action: "created", Message: "collection"}))
+		if dryRun {
+			return "", nil
+		}
+		// Copies in a collection that no longer exists are gone too
+		for srcID, doc := range m.state.Documents {
+			if mapped && doc.CollectionID == id {
+				delete(m.state.Documents, srcID)
+			}
+		}
+		m.state.Collections[coll.ID] = response.Data.ID
+		return response.Data.ID, m.state.save(m.file)
+	}
+
+	if existing.Name != coll.Name || existing.Description != coll.Description ||
+		existing.Color != coll.Color || existing.Icon != coll.Icon || existing.Permission != coll.Permission {
+		payload := collectionPayload(coll)
+		payload["id"] = id
+		if err := m.target.Call("collections.update", payload, nil); err != nil {
+			return "", fmt.Errorf("updating collection %s: %w", coll.Name, err)
+		}
+		m.entries = append(m.entries, dryRunStatus(statusEntry{ID: id, Title: coll.Name, Action: "updated", Message: "collection"}))
+	}
+	return id, nil
+}
+
+// collectionPayload holds the settings of coll copied to the target.
+// Outline rejects an empty permission, so private collections leave it out.
+func collectionPayload(coll api.Collection) map[string]any {
+	payload := map[string]any{
+		"name":        coll.Name,
+		"description": coll.Description,
+		"color":       coll.Color,
+		"icon":        coll.Icon,
+	}
+	if coll.Permission != "" {
+		payload["permission"] = coll.Permission
+	}
+	return payload
+}
+
+// documents copies nodes and everything below them into the collection
+// with the given target ID, below parentID
+func (m *mirrorer) documents(nodes []api.NavigationNode, collectionID string, parentID string, progress *progressBar) error {
+	for _, n := range nodes {
+		doc, err := m.source.GetDocument(n.ID)
+		if err != nil {
+			m.entries = append(m.entries, statusEntry{ID: n.ID, Title: n.Title, Action: "failed", Message: err.Error()})
+			return fmt.Errorf("fetching %s: %w", n.Title, err)
+		}
+		m.texts[doc.ID] = doc.Text
+
+		copied, err := m.document(doc, collectionID, parentID)
+		if err != nil {
+			m.entries = append(m.entries, statusEntry{ID: n.ID, Title: n.Title, Action: "failed", Message: err.Error()})
+			return fmt.Errorf("mirroring %s: %w", n.Title, err)
+		}
+		progress.increment()
+
+		if err := m.documents(n.Children, collectionID, copied.ID, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// document creates or updates the copy of doc
+func (m *mirrorer) document(doc *api.Document, collectionID string, parentID string) (*mirroredDocument, error) {
+	copied := m.state.Documents[doc.ID]
+	copyID := ""
+	if copied != nil {
+		copyID = copied.ID
+	}
+	text, resolved, err := m.rewrite(doc.Text, copyID)
+	if err != nil {
+		return nil, err
+	}
+	hash := contentHash([]byte(text))
+
+	var actions []string
+	if copied != nil {
+		actions, err = m.update(copied, doc, text, hash, collectionID, parentID)
+		var apiErr *api.Error
+		if errors.As(err, &apiErr) && apiErr.NotFound() {
+			// The copy was deleted on the target, so make a new one. Copies
+			// linking to the old one are relinked once every document is
+			// copied.
+			logger.Warn("copy deleted on the target, recreating it", "id", copied.ID, "title", doc.Title)
+			delete(m.state.Documents, doc.ID)
+			for _, other := range m.state.Documents {
+				other.Pending = true
+			}
+			copied, actions = nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if copied == nil {
+		created, err := m.target.CreateDocument(doc.Title, text, collectionID, parentID)
+		if err != nil {
+			return nil, err
+		}
+		copied = &mirroredDocument{ID: created.ID, URLID: created.URLID}
+		actions = append(actions, "created")
+		if doc.Icon != "" || doc.Emoji != "" {
+			if err := m.setIcon(copied.ID, doc); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	entry := statusEntry{ID: copied.ID, Title: doc.Title, Action: "unchanged"}
+	if len(actions) > 0 {
+		entry = dryRunStatus(statusEntry{ID: copied.ID, Title: doc.Title, Action: strings.Join(actions, ",")})
+	}
+	m.results[doc.ID] = len(m.entries)
+	m.entries = append(m.entries, entry)
+	if dryRun {
+		return copied, nil
+	}
+
+	updated := *copied
+	updated.CollectionID, updated.ParentID, updated.Title = collectionID, parentID, doc.Title
+	updated.Icon, updated.Emoji = doc.Icon, doc.Emoji
+	updated.Hash, updated.Pending = hash, !resolved
+	m.state.Documents[doc.ID] = &updated
+	return &updated, m.state.save(m.file)
+}
+
+// update brings an existing copy in line with its source document and
+// returns what was done
+func (m *mirrorer) update(copied *mirroredDocument, doc *api.Document, text string, hash string, collectionID string, parentID string) ([]string, error) {
+	var actions []string
+	if copied.CollectionID != collectionID || copied.ParentID != parentID {
+		if err := m.target.MoveDocument(copied.ID, collectionID, parentID); err != nil {
+			return nil, err
+		}
+		actions = append(actions, "moved")
+	}
+	if copied.Title != doc.Title {
+		if err := m.target.RenameDocument(copied.ID, doc.Title); err != nil {
+			return nil, err
+		}
+		actions = append(actions, "renamed")
+	}
+	updated := false
+	if copied.Hash != hash {
+		if err := m.target.UpdateDocument(copied.ID, text); err != nil {
+			return nil, err
+		}
+		updated = true
+	}
+	if copied.Icon != doc.Icon || copied.Emoji != doc.Emoji {
+		if err := m.setIcon(copied.ID, doc); err != nil {
+			return nil, err
+		}
+		updated = true
+	}
+	if updated {
+		actions = append(actions, "updated")
+	}
+	return actions, nil
+}
+
+// setIcon gives the copy with the given ID the icon and emoji of doc
+func (m *mirrorer) setIcon(id string, doc *api.Document) error {
+	payload := struct {
+		ID    string `json:"id"`
+		Icon  string `json:"icon"`
+		Emoji string `json:"emoji"`
+	}{
+		ID:    id,
+		Icon:  doc.Icon,
+		Emoji: doc.Emoji,
+	}
+	return m.target.Call("documents.update", payload, nil)
+}
+
+// relink rewrites the copies still linking to documents that weren't
+// copied at the time
+func (m *mirrorer) relink(nodes []api.NavigationNode) error {
+	for _, n := range nodes {
+		copied := m.state.Documents[n.ID]
+		if copied != nil && copied.Pending {
+			text, resolved, err := m.rewrite(m.texts[n.ID], copied.ID)
+			if err != nil {
+				return fmt.Errorf("mirroring %s: %w", n.Title, err)
+			}
+			if hash := contentHash([]byte(text)); hash != copied.Hash {
+				if err := m.target.UpdateDocument(copied.ID, text); err != nil {
+					return fmt.Errorf("rewriting links in %s: %w", n.Title, err)
+				}
+				copied.Hash = hash
+				if i, ok := m.results[n.ID]; ok && m.entries[i].Action == "unchanged" {
+					m.entries[i].Action = "updated"
+				}
+			}
+			copied.Pending = !resolved
+			if err := m.state.save(m.file); err != nil {
+				return err
+			}
+		}
+		if err := m.relink(n.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewrite copies the attachments text refers to and points its links at
+// the target. It reports whether every link to a mirrored document could
+// be resolved.
+func (m *mirrorer) rewrite(text string, copyID string) (string, bool, error) {
+	ids := make(map[string]string)
+	for _, id := range assets.RemoteIDs(text) {
+		copied, ok := m.state.Attachments[id]
+		if !ok {
+			file, err := m.source.DownloadAttachment(id)
+			if err != nil {
+				return "", false, fmt.Errorf("downloading attachment %s: %w", id, err)
+			}
+			att, err := m.target.CreateAttachment(file.Name, file.ContentType, file.Data, copyID)
+			if err != nil {
+				return "", false, fmt.Errorf("uploading attachment %s: %w", id, err)
+			}
+			copied = att.ID
+			if !dryRun {
+				m.state.Attachments[id] = copied
+			}
+		}
+		ids[id] = copied
+	}
+	text = assets.Relink(text, ids)
+
+	text, resolved := relinkDocuments(text, m.state.Source, func(ref string) (string, bool) {
+		srcID, mirrored := m.sourceIDs[ref]
+		if !mirrored {
+			return "", false
+		}
+		if copied := m.state.Documents[srcID]; copied != nil {
+			return copied.URLID, true
+		}
+		return "", true
+	})
+	return text, resolved, nil
+}
+
+// relinkDocuments points links in text to documents on source at their
+// copies. lookup maps the ID or url-id in a link to the url-id of the copy,
+// reporting whether the document is copied at all; an empty url-id means
+// the copy doesn't exist yet, and false is returned to try again later.
+// Relative links to documents that aren't copied are made absolute so they
+// keep pointing at source.
+func relinkDocuments(text string, source string, lookup func(ref string) (string, bool)) (string, bool) {
+	sourceHost := ""
+	if u, err := url.Parse(source); err == nil {
+		sourceHost = u.Host
+	}
+
+	resolved := true
+	for _, target := range assets.Links(text) {
+		u, err := url.Parse(target)
+		if err != nil || (u.Host != "" && u.Host != sourceHost) {
+			continue
+		}
+		ref, ok := api.LinkedDocumentID(target)
+		if !ok {
+			continue
+		}
+		urlID, copied := lookup(ref)
+		if !copied {
+			if u.Host == "" {
+				text = assets.ReplaceLink(text, target, source+target)
+			}
+			continue
+		}
+		if urlID == "" {
+			resolved = false
+			continue
+		}
+		link := "/doc/" + urlID
+		if u.Fragment != "" {
+			link += "#" + u.Fragment
+		}
+		text = assets.ReplaceLink(text, target, link)
+	}
+	return text, resolved
+}
+
+func loadMirrorState(filename string) (*mirrorState, error) {
+	state := &mirrorState{}
+	data, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading mirror map: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("parsing mirror map: %w", err)
+		}
+	}
+	if state.Collections == nil {
+		state.Collections = make(map[string]string)
+	}
+	if state.Documents == nil {
+		state.Documents = make(map[string]*mirroredDocument)
+	}
+	if state.Attachments == nil {
+		state.Attachments = make(map[string]string)
+	}
+	return state, nil
+}
+
+func (s *mirrorState) save(filename string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding mirror map: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := writeFileAtomic(filename, data, 0600); err != nil {
+		return fmt.Errorf("writing mirror map: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	mirrorCmd.Flags().StringVar(&mirrorFrom, "from", "", "profile of the instance to copy from")
+	mirrorCmd.Flags().StringVar(&mirrorTo, "to", "", "profile of the instance to copy to")
+	mirrorCmd.Flags().StringSliceVar(&mirrorCollections, "collection", nil, "collection to mirror by ID, url-id or name; repeat for several (default all)")
+	mirrorCmd.Flags().StringVar(&mirrorMapFile, "map", "", "file mapping source IDs to their copies (default ~/.outline-cli/mirror-<from>-<to>.json)")
+
+	RootCmd.AddCommand(mirrorCmd)
+}
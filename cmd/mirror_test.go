@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/config"
+	"outline-cli/outlinetest"
+)
+
+func TestMirror(t *testing.T) {
+	source, target := useFakeServer(t), useFakeServer(t)
+	sourceCfg, targetCfg := source.Config(), target.Config()
+	sourceCfg.Backups.Disabled = true
+	targetCfg.Backups.Disabled = true
+	useConfig(t, &config.Config{Profiles: map[string]*config.Config{"old": sourceCfg, "new": targetCfg}})
+
+	runbooks := source.AddCollection("Runbooks")
+	other := source.AddCollection("Other")
+	secret := source.AddDocument(api.Document{Title: "Secret", Text: "Not mirrored", CollectionID: other.ID})
+	att := source.AddAttachment("chart.png", "image/png", []byte("chart"), "")
+	parent := source.AddDocument(api.Document{Title: "Databases", CollectionID: runbooks.ID})
+	child := source.AddDocument(api.Document{
+		Title:            "Failover",
+		Text:             "Back to [databases](" + source.URL + "/doc/databases-" + parent.URLID + ")",
+		CollectionID:     runbooks.ID,
+		ParentDocumentID: parent.ID,
+	})
+	parentText := "See [failover](/doc/failover-" + child.URLID + "#steps) and [secret](/doc/secret-" + secret.URLID + ")\n\n![chart](" + att.URL + ")"
+	updateText(t, source, parent.ID, parentText)
+	client := api.DefaultClientFactory(source.Config())
+	if err := client.Call("collections.update", map[string]string{"id": runbooks.ID, "color": "#FF5733", "icon": "server", "permission": "read"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call("documents.update", map[string]string{"id": child.ID, "icon": "🔥"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mapFile := filepath.Join(t.TempDir(), "map.json")
+	args := []string{"mirror", "--from", "old", "--to", "new", "--collection", "Runbooks", "--map", mapFile}
+	RootCmd.SetArgs(args)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	collections := target.Collections()
+	if len(collections) != 1 || collections[0].Name != "Runbooks" {
+		t.Fatalf("expected only Runbooks on the target, got %+v", collections)
+	}
+	if c := collections[0]; c.Color != "#FF5733" || c.Icon != "server" || c.Permission != "read" {
+		t.Errorf("expected the collection's color, icon and permission copied, got %+v", c)
+	}
+	copies := make(map[string]api.Document)
+	for _, doc := range target.Documents() {
+		copies[doc.Title] = doc
+	}
+	databases, failover := copies["Databases"], copies["Failover"]
+	if len(copies) != 2 || failover.ParentDocumentID != databases.ID {
+		t.Fatalf("expected the hierarchy copied, got %+v", copies)
+	}
+	if !strings.Contains(databases.Text, "[failover](/doc/"+failover.URLID+"#steps)") {
+		t.Errorf("expected the forward link rewritten to the copy, got %q", databases.Text)
+	}
+	if !strings.Contains(databases.Text, "[secret]("+source.URL+"/doc/secret-"+secret.URLID+")") {
+		t.Errorf("expected the link to an unmirrored document to point at the source, got %q", databases.Text)
+	}
+	if failover.Icon != "🔥" {
+		t.Errorf("expected the document's icon copied, got %q", failover.Icon)
+	}
+	if failover.Text != "Back to [databases](/doc/"+databases.URLID+")" {
+		t.Errorf("expected the absolute source link rewritten, got %q", failover.Text)
+	}
+	ids := strings.Split(databases.Text, "attachments.redirect?id=")
+	if len(ids) != 2 {
+		t.Fatalf("expected one attachment link, got %q", databases.Text)
+	}
+	copied, ok := target.Attachment(strings.TrimSuffix(ids[1], ")"))
+	if !ok || string(copied.Data) != "chart" {
+		t.Errorf("expected the attachment copied to the target, got %+v", copied)
+	}
+
+	// A second run updates the copies in place
+	updateText(t, source, parent.ID, parentText+"\n\nUpdated")
+	renameDocument(t, source, child.ID, "Failover v2")
+	resetCommands()
+	RootCmd.SetArgs(args)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error on re-run: %v", err)
+	}
+
+	docs := target.Documents()
+	if len(docs) != 2 {
+		t.Fatalf("expected the re-run not to duplicate documents, got %d", len(docs))
+	}
+	if doc, _ := target.Document(databases.ID); !strings.HasSuffix(doc.Text, "Updated") {
+		t.Errorf("expected the copy updated, got %q", doc.Text)
+	}
+	if doc, _ := target.Document(failover.ID); doc.Title != "Failover v2" {
+		t.Errorf("expected the copy renamed, got %q", doc.Title)
+	}
+	if n := strings.Count(strings.Join(target.Calls(), " "), "attachments.create"); n != 1 {
+		t.Errorf("expected the attachment copied once, got %d uploads", n)
+	}
+
+	// A copy deleted on the target is recreated rather than failing the run
+	if err := api.DefaultClientFactory(target.Config()).DeleteDocument(failover.ID); err != nil {
+		t.Fatal(err)
+	}
+	updateText(t, source, child.ID, "Promote the replica")
+	resetCommands()
+	RootCmd.SetArgs(args)
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error after deleting a copy: %v", err)
+	}
+
+	copies = make(map[string]api.Document)
+	for _, doc := range target.Documents() {
+		copies[doc.Title] = doc
+	}
+	recreated := copies["Failover v2"]
+	if len(copies) != 2 || recreated.Text != "Promote the replica" || recreated.ParentDocumentID != databases.ID {
+		t.Fatalf("expected the deleted copy recreated, got %+v", copies)
+	}
+	if doc, _ := target.Document(databases.ID); !strings.Contains(doc.Text, "[failover](/doc/"+recreated.URLID+"#steps)") {
+		t.Errorf("expected the link to the deleted copy rewritten, got %q", doc.Text)
+	}
+}
+
+func updateText(t *testing.T, srv *outlinetest.Server, id string, text string) {
+	t.Helper()
+	if err := api.DefaultClientFactory(srv.Config()).UpdateDocument(id, text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func renameDocument(t *testing.T, srv *outlinetest.Server, id string, title string) {
+	t.Helper()
+	if err := api.DefaultClientFactory(srv.Config()).RenameDocument(id, title); err != nil {
+		t.Fatal(err)
+	}
+}
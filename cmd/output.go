@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"io"
+
+	"outline-cli/output"
+)
+
+var outputFormat string
+var outputFields []string
+var outputTemplate string
+
+// printer renders command results. It is configured by the root command's
+// pre-run hook from the --output, --fields and --format flags.
+var printer *output.Printer
+
+// statusEntry reports the outcome of an operation on a single document
+type statusEntry struct {
+	ID      string `json:"id"`
+	Title   string `json:"title,omitempty"`
+	Action  string `json:"action"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// searchHit is a flattened documents.search result
+type searchHit struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Ranking float64 `json:"ranking"`
+	Context string  `json:"context"`
+}
+
+// configInfo is the configuration summary shown by the debug command
+type configInfo struct {
+	OutlineURL string `json:"outline_url"`
+	APIKey     string `json:"api_key"`
+}
+
+func newPrinter(w io.Writer) (*output.Printer, error) {
+	return output.New(w, output.Options{
+		Format:   outputFormat,
+		Fields:   outputFields,
+		Template: outputTemplate,
+	})
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"outline-cli/api"
+
+	"github.com/spf13/cobra"
+)
+
+// titleLookup is the --title given to commands that accept a document
+// reference
+var titleLookup string
+
+// resolveDocumentID turns a document URL, share URL, url-id or UUID into
+// the document's ID. UUIDs are used as they are; everything else is looked
+// up with documents.info.
+func resolveDocumentID(client api.Client, arg string) (string, error) {
+	ref, err := api.ParseDocumentRef(arg)
+	if err != nil {
+		return "", err
+	}
+	if ref.ShareID == "" && api.IsUUID(ref.ID) {
+		return ref.ID, nil
+	}
+
+	var doc *api.Document
+	if ref.ShareID != "" {
+		doc, err = client.GetSharedDocument(ref.ShareID, ref.ID)
+	} else {
+		doc, err = client.GetDocument(ref.ID)
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", arg, err)
+	}
+	logger.Debug("resolved document", "ref", arg, "id", doc.ID)
+	return doc.ID, nil
+}
+
+// findDocumentByTitle searches for the document with exactly the given
+// title. When several match, the user picks one on a terminal; otherwise
+// the candidates are listed in the error.
+func findDocumentByTitle(cmd *cobra.Command, client api.Client, title string) (string, error) {
+	results, err := client.SearchTitles(title)
+	if err != nil {
+		return "", fmt.Errorf("searching documents: %w", err)
+	}
+
+	var matches []api.Document
+	var similar []string
+	for _, doc := range results {
+		if doc.Title == title {
+			matches = append(matches, doc)
+		} else if strings.EqualFold(doc.Title, title) {
+			similar = append(similar, fmt.Sprintf("%q (%s)", doc.Title, doc.ID))
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		if len(similar) > 0 {
+			return "", fmt.Errorf("no document titled %q; did you mean %s?", title, strings.Join(similar, " or "))
+		}
+		return "", fmt.Errorf("no document titled %q", title)
+	case 1:
+		return matches[0].ID, nil
+	}
+
+	names := make(map[string]string)
+	if collections, err := client.ListCollections(); err == nil {
+		for _, c := range collections {
+			names[c.ID] = c.Name
+		}
+	}
+
+	var list strings.Builder
+	for i, doc := range matches {
+		fmt.Fprintf(&list, "  %d) %s  collection: %s  updated: %s\n",
+			i+1, doc.ID, names[doc.CollectionID], doc.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+
+	if !isTerminal(cmd.InOrStdin()) {
+		return "", fmt.Errorf("%d documents are titled %q, pass one of their IDs instead:\n%s", len(matches), title, strings.TrimSuffix(list.String(), "\n"))
+	}
+	return chooseDocument(cmd.InOrStdin(), cmd.ErrOrStderr(), title, matches, list.String())
+}
+
+// chooseDocument asks the user to pick one of several documents
+func chooseDocument(in io.Reader, out io.Writer, title string, matches []api.Document, list string) (string, error) {
+	fmt.Fprintf(out, "%d documents are titled %q:\n%s", len(matches), title, list)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "Choose one [1-%d]: ", len(matches))
+		if !scanner.Scan() {
+			return "", fmt.Errorf("no document chosen")
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err == nil && n >= 1 && n <= len(matches) {
+			return matches[n-1].ID, nil
+		}
+	}
+}
+
+// documentID resolves the single document a command works on, given as its
+// first argument or with --title
+func documentID(cmd *cobra.Command, client api.Client, args []string) (string, error) {
+	if titleLookup != "" {
+		return findDocumentByTitle(cmd, client, titleLookup)
+	}
+	return resolveDocumentID(client, args[0])
+}
+
+// documentArgs validates the arguments of a command that takes a document
+// reference, or --title instead, followed by up to extra other arguments
+func documentArgs(extra int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if titleLookup != "" {
+			return cobra.MaximumNArgs(extra)(cmd, args)
+		}
+		return cobra.RangeArgs(1, 1+extra)(cmd, args)
+	}
+}
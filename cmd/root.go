@@ -1,21 +1,34 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"outline-cli/api"
+	"outline-cli/assets"
 	"outline-cli/config"
-	"strings"
+	"outline-cli/diff"
+	"outline-cli/workspace"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var clientFactory api.ClientFactory = api.DefaultClientFactory
+
+// stdioPath is the file argument that selects stdin or stdout
+const stdioPath = "-"
+
 var verbose bool
+var logLevel string
+var logFormat string
+
+// recordDir and replayDir select a cassette directory for --record and
+// --replay
+var recordDir string
+var replayDir string
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -25,67 +38,393 @@ var RootCmd = &cobra.Command{
 You can pull documents, edit them locally, and push changes back to Outline.`,
 	SilenceErrors: true,
 	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		level := logLevel
+		if verbose && !cmd.Flags().Changed("log-level") {
+			level = "debug"
+		}
+
+		l, err := newLogger(os.Stderr, level, logFormat)
+		if err != nil {
+			return err
+		}
+		logger = l
+
+		p, err := newPrinter(cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		printer = p
+		pendingChanges.Store(0)
+		apiMetrics.Reset()
+
+		if recordDir != "" && replayDir != "" {
+			return fmt.Errorf("--record and --replay cannot be used together")
+		}
+		return nil
+	},
+	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		logMetrics()
+		if n := pendingChanges.Load(); dryRun && n > 0 {
+			return fmt.Errorf("%w (%d pending)", ErrChangesPending, n)
+		}
+		return nil
+	},
 }
 
+// newClient builds an API client wired to the invocation's logger. With
+// --dry-run it reports mutations instead of sending them; otherwise remote
+// content is backed up before it is overwritten.
+func newClient(cfg *config.Config) api.Client {
+	opts := []api.Option{api.WithLogger(logger), api.WithMetrics(apiMetrics)}
+	if dryRun {
+		opts = append(opts, api.WithDryRun(reportMutation))
+	}
+	if recordDir != "" {
+		opts = append(opts, api.WithRecorder(recordDir))
+	}
+	if replayDir != "" {
+		opts = append(opts, api.WithReplay(replayDir))
+	}
+	if cfg.RateLimit > 0 {
+		opts = append(opts, api.WithRateLimit(time.Duration(cfg.RateLimit)))
+	}
+	if cfg.CacheTTL > 0 {
+		opts = append(opts, api.WithCache(time.Duration(cfg.CacheTTL)))
+	}
+
+	client := clientFactory(cfg, opts...)
+	if dryRun || cfg.Backups.Disabled {
+		return client
+	}
+	return &backupClient{Client: client, cfg: cfg}
+}
+
+var pullOut string
+var pullSkipAttachments bool
+var pullAll bool
+var pullForce bool
+
 var pullCmd = &cobra.Command{
-	Use:   "pull [docID]",
-	Short: "Pull a document from Outline",
-	Args:  cobra.ExactArgs(1),
+	Use:   "pull [docID|URL...]",
+	Short: "Pull documents from Outline",
+	Long: `Pull documents from Outline and save them as Markdown.
+
+By default each document is written to <docID>.md in the working directory.
+For a single document, use -o to choose another path, or -o - to stream it
+to stdout. Pass several IDs, or --all for every document, to pull them
+concurrently.
+
+Documents can be given by ID, url-id, document or share URL, or found by
+exact title with --title. Files are always named after the document ID.
+
+Attachments referenced by the document are downloaded into an assets
+directory next to the file and links are rewritten to point at them, so
+images render locally. Push reverses the rewrite.
+
+Files are replaced atomically. Pull refuses to overwrite a file that was
+edited since it was last pulled or pushed; --force overwrites it and keeps
+the local version as <file>.orig.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pullAll || titleLookup != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if pullOut != "" && (pullAll || len(args) > 1) {
+			return fmt.Errorf("-o can only be used when pulling a single document")
+		}
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		client := clientFactory(cfg)
-		doc, err := client.GetDocument(args[0], verbose)
+		client := newClient(cfg)
+
+		if pullAll || len(args) > 1 {
+			ids := args
+			if pullAll {
+				docs, err := client.ListDocuments()
+				if err != nil {
+					return fmt.Errorf("listing documents: %w", err)
+				}
+				ids = make([]string, len(docs))
+				for i, doc := range docs {
+					ids[i] = doc.ID
+				}
+			}
+
+			return runBulk(cmd, "pull", ids, func(ref string) (statusEntry, error) {
+				id, err := resolveDocumentID(client, ref)
+				if err != nil {
+					return statusEntry{}, err
+				}
+				filename, err := workspace.DocumentFile(id)
+				if err != nil {
+					return statusEntry{}, err
+				}
+				return pullDocument(client, id, filename)
+			})
+		}
+
+		id, err := documentID(cmd, client, args)
 		if err != nil {
-			return fmt.Errorf("fetching document: %w", err)
+			return err
+		}
+
+		if pullOut == stdioPath {
+			doc, err := client.GetDocument(id)
+			if err != nil {
+				return fmt.Errorf("fetching document: %w", err)
+			}
+			if _, err := io.WriteString(cmd.OutOrStdout(), doc.Text); err != nil {
+				return fmt.Errorf("writing to stdout: %w", err)
+			}
+			logger.Info("pulled document", "id", id, "path", stdioPath)
+			return nil
 		}
 
-		filename := fmt.Sprintf("%s.md", args[0])
-		if err := os.WriteFile(filename, []byte(doc.Text), 0644); err != nil {
-			return fmt.Errorf("writing file: %w", err)
+		filename := pullOut
+		if filename == "" {
+			if filename, err = workspace.DocumentFile(id); err != nil {
+				return err
+			}
 		}
 
-		fmt.Printf("Successfully pulled document to %s\n", filename)
-		return nil
+		entry, err := pullDocument(client, id, filename)
+		if err != nil {
+			return err
+		}
+		return printer.Print(entry, "id", "action", "path", "message")
 	},
 }
 
+// pullDocument fetches a document and writes it to filename
+func pullDocument(client api.Client, docID string, filename string) (statusEntry, error) {
+	doc, err := client.GetDocument(docID)
+	if err != nil {
+		return statusEntry{}, fmt.Errorf("fetching document: %w", err)
+	}
+
+	text := doc.Text
+	if !pullSkipAttachments {
+		text, err = downloadAttachments(client, text, filepath.Dir(filename))
+		if err != nil {
+			return statusEntry{}, err
+		}
+	}
+
+	entry := statusEntry{
+		ID:     docID,
+		Title:  doc.Title,
+		Action: "pulled",
+		Path:   filename,
+	}
+
+	if err := checkOverwrite(filename, text); err != nil {
+		if !errors.Is(err, errLocalChanges) {
+			return statusEntry{}, err
+		}
+		if !pullForce {
+			return statusEntry{}, fmt.Errorf("%w; push them first or pass --force to overwrite", err)
+		}
+
+		old, err := os.ReadFile(filename)
+		if err != nil {
+			return statusEntry{}, fmt.Errorf("reading file: %w", err)
+		}
+		if err := writeFileAtomic(filename+".orig", old, 0644); err != nil {
+			return statusEntry{}, fmt.Errorf("saving local changes: %w", err)
+		}
+		entry.Message = "local changes saved to " + filename + ".orig"
+	}
+
+	if err := writeFileAtomic(filename, []byte(text), 0644); err != nil {
+		return statusEntry{}, fmt.Errorf("writing file: %w", err)
+	}
+	if err := recordPulled(filename, []byte(text)); err != nil {
+		return statusEntry{}, err
+	}
+
+	return entry, nil
+}
+
+var pushFile string
+var pushAll bool
+
 var pushCmd = &cobra.Command{
-	Use:   "push [docID]",
+	Use:   "push [docID|URL...]",
 	Short: "Push local changes to Outline",
-	Args:  cobra.ExactArgs(1),
+	Long: `Push local Markdown to Outline documents.
+
+By default the content is read from <docID>.md in the working directory.
+For a single document, use -f to choose another path, or -f - to read it
+from stdin. Pass several IDs, or --all for every <docID>.md file in the
+working directory, to push them concurrently.
+
+Documents can be given by ID, url-id, document or share URL, or found by
+exact title with --title.
+
+Links to local images and files are uploaded as attachments first, and
+links into the assets directory created by pull are restored.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if pushAll || titleLookup != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if pushFile != "" && (pushAll || len(args) > 1) {
+			return fmt.Errorf("-f can only be used when pushing a single document")
+		}
+
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		client := clientFactory(cfg)
-		filename := fmt.Sprintf("%s.md", args[0])
-		content, err := os.ReadFile(filename)
+		client := newClient(cfg)
+
+		if pushAll || len(args) > 1 {
+			ids := args
+			if pushAll {
+				files, err := filepath.Glob("*.md")
+				if err != nil {
+					return fmt.Errorf("finding documents: %w", err)
+				}
+				ids = make([]string, 0, len(files))
+				for _, file := range files {
+					id, err := workspace.IDFromFile(file)
+					if err != nil {
+						logger.Warn("skipping file not named after a document", "path", file)
+						continue
+					}
+					ids = append(ids, id)
+				}
+			}
+
+			return runBulk(cmd, "push", ids, func(ref string) (statusEntry, error) {
+				id, err := resolveDocumentID(client, ref)
+				if err != nil {
+					return statusEntry{}, err
+				}
+				filename, err := workspace.DocumentFile(id)
+				if err != nil {
+					return statusEntry{}, err
+				}
+				content, err := os.ReadFile(filename)
+				if err != nil {
+					return statusEntry{}, fmt.Errorf("reading file: %w", err)
+				}
+				return pushDocument(client, id, string(content), filename)
+			})
+		}
+
+		id, err := documentID(cmd, client, args)
 		if err != nil {
-			return fmt.Errorf("reading file: %w", err)
+			return err
 		}
 
-		if err := client.UpdateDocument(args[0], string(content), verbose); err != nil {
-			return fmt.Errorf("updating document: %w", err)
+		filename := pushFile
+		if filename == "" {
+			if filename, err = workspace.DocumentFile(id); err != nil {
+				return err
+			}
 		}
 
-		fmt.Printf("Successfully pushed changes to document %s\n", args[0])
-		return nil
+		var content []byte
+		if filename == stdioPath {
+			content, err = io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return fmt.Errorf("reading stdin: %w", err)
+			}
+		} else {
+			content, err = os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("reading file: %w", err)
+			}
+		}
+
+		entry, err := pushDocument(client, id, string(content), filename)
+		if err != nil {
+			return err
+		}
+		return printer.Print(entry, "id", "action", "path", "message")
 	},
 }
 
+// pushDocument uploads content read from filename to a document
+func pushDocument(client api.Client, docID string, content string, filename string) (statusEntry, error) {
+	dir := filepath.Dir(filename)
+	if filename == stdioPath {
+		dir = "."
+	}
+	text, err := uploadAttachments(client, content, dir, docID)
+	if err != nil {
+		return statusEntry{}, err
+	}
+
+	if err := client.UpdateDocument(docID, text); err != nil {
+		return statusEntry{}, fmt.Errorf("updating document: %w", err)
+	}
+	if filename != stdioPath && !dryRun {
+		if err := recordPulled(filename, []byte(content)); err != nil {
+			return statusEntry{}, err
+		}
+	}
+
+	return dryRunStatus(statusEntry{
+		ID:     docID,
+		Action: "pushed",
+		Path:   filename,
+	}), nil
+}
+
+var diffFile string
+
 var diffCmd = &cobra.Command{
-	Use:   "diff [docID]",
+	Use:   "diff [docID|URL]",
 	Short: "Compare local and remote versions",
-	Args:  cobra.ExactArgs(1),
+	Long: `Print a unified diff from the remote document to the local file.
+
+The local file is <docID>.md unless -f is given, with links into the assets
+directory restored to attachment URLs before comparing. Nothing is printed
+when both sides match.`,
+	Args: documentArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// TODO: Implement diff logic
-		return nil
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		id, err := documentID(cmd, client, args)
+		if err != nil {
+			return err
+		}
+
+		filename := diffFile
+		if filename == "" {
+			if filename, err = workspace.DocumentFile(id); err != nil {
+				return err
+			}
+		}
+
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		doc, err := client.GetDocument(id)
+		if err != nil {
+			return fmt.Errorf("fetching document: %w", err)
+		}
+
+		local := assets.Unlocalize(string(content))
+		_, err = io.WriteString(cmd.OutOrStdout(), diff.Unified("remote/"+id, "local/"+filename, doc.Text, local))
+		return err
 	},
 }
 
@@ -98,10 +437,10 @@ var debugCmd = &cobra.Command{
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		fmt.Printf("Configuration:\n")
-		fmt.Printf("  Outline URL: %s\n", cfg.OutlineURL)
-		fmt.Printf("  API Key: %s\n", maskAPIKey(cfg.APIKey))
-		return nil
+		return printer.Print(configInfo{
+			OutlineURL: cfg.OutlineURL,
+			APIKey:     maskAPIKey(cfg.APIKey),
+		}, "outline_url", "api_key")
 	},
 }
 
@@ -114,133 +453,121 @@ var listCmd = &cobra.Command{
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		client := clientFactory(cfg)
-		docs, err := client.ListDocuments(verbose)
+		client := newClient(cfg)
+		docs, err := client.ListDocuments()
 		if err != nil {
 			return fmt.Errorf("listing documents: %w", err)
 		}
 
-		for _, doc := range docs {
-			fmt.Printf("%s: %s\n", doc.ID, doc.Title)
+		if docs == nil {
+			docs = []api.Document{}
 		}
-		return nil
+		return printer.Print(docs, "id", "title")
 	},
 }
 
-var testCmd = &cobra.Command{
-	Use:   "test",
-	Short: "Test API connection",
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "List available collections",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		url := fmt.Sprintf("%s/api/auth.info", normalizeURL(cfg.OutlineURL))
-
-		// Create an empty payload since it's a POST request
-		payload := struct{}{}
-		body, err := json.Marshal(payload)
+		client := newClient(cfg)
+		collections, err := client.ListCollections()
 		if err != nil {
-			return fmt.Errorf("marshaling payload: %w", err)
+			return fmt.Errorf("listing collections: %w", err)
 		}
 
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-		if err != nil {
-			return fmt.Errorf("creating request: %w", err)
+		if collections == nil {
+			collections = []api.Collection{}
 		}
+		return printer.Print(collections, "id", "name")
+	},
+}
 
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("Content-Type", "application/json")
-
-		if verbose {
-			fmt.Printf("Making request to: %s\n", url)
-			fmt.Printf("Request headers:\n")
-			for k, v := range req.Header {
-				fmt.Printf("  %s: %s\n", k, v)
-			}
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search documents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
 		}
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		client := newClient(cfg)
+		results, err := client.SearchDocuments(args[0])
 		if err != nil {
-			return fmt.Errorf("executing request: %w", err)
+			return fmt.Errorf("searching documents: %w", err)
 		}
-		defer resp.Body.Close()
 
-		body, err = io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("reading response: %w", err)
+		hits := make([]searchHit, 0, len(results))
+		for _, r := range results {
+			hits = append(hits, searchHit{
+				ID:      r.Document.ID,
+				Title:   r.Document.Title,
+				Ranking: r.Ranking,
+				Context: r.Context,
+			})
 		}
+		return printer.Print(hits, "id", "title", "context")
+	},
+}
 
-		if verbose {
-			fmt.Printf("Response status: %s\n", resp.Status)
-			fmt.Printf("Response body: %s\n", string(body))
+var testCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Test API connection",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			var apiError struct {
-				Error   string `json:"error"`
-				Message string `json:"message"`
-			}
-			if err := json.Unmarshal(body, &apiError); err == nil {
-				return fmt.Errorf("API error: %s - %s", apiError.Error, apiError.Message)
-			}
-			return fmt.Errorf("API error: %s", string(body))
+		if err := newClient(cfg).Call("auth.info", nil, nil); err != nil {
+			return fmt.Errorf("testing connection: %w", err)
 		}
 
-		fmt.Println("API connection successful!")
-		return nil
+		return printer.Print(statusEntry{
+			Action:  "tested",
+			Message: "API connection successful",
+		}, "action", "message")
 	},
 }
 
 var updateCmd = &cobra.Command{
-	Use:   "update [docID]",
+	Use:   "update [docID|URL]",
 	Short: "Update document metadata",
-	Args:  cobra.ExactArgs(1),
+	Args:  documentArgs(0),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.LoadConfig()
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		url := fmt.Sprintf("%s/api/documents.update", normalizeURL(cfg.OutlineURL))
+		client := newClient(cfg)
+		id, err := documentID(cmd, client, args)
+		if err != nil {
+			return err
+		}
+
 		payload := struct {
 			ID      string `json:"id"`
 			Publish bool   `json:"publish"`
 		}{
-			ID:      args[0],
+			ID:      id,
 			Publish: true,
 		}
-
-		body, err := json.Marshal(payload)
-		if err != nil {
-			return fmt.Errorf("marshaling payload: %w", err)
-		}
-
-		req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-		if err != nil {
-			return fmt.Errorf("creating request: %w", err)
-		}
-
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", cfg.APIKey))
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return fmt.Errorf("executing request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		if err := client.Call("documents.update", payload, nil); err != nil {
+			return fmt.Errorf("updating document: %w", err)
 		}
 
-		fmt.Printf("Successfully updated document %s\n", args[0])
-		return nil
+		return printer.Print(dryRunStatus(statusEntry{
+			ID:     id,
+			Action: "updated",
+		}), "id", "action", "message")
 	},
 }
 
@@ -254,19 +581,22 @@ var createCmd = &cobra.Command{
 			return fmt.Errorf("loading config: %w", err)
 		}
 
-		client := clientFactory(cfg)
+		client := newClient(cfg)
 		doc, err := client.CreateDocument(
 			args[0],
 			"# "+args[0]+"\n\nNew document created via CLI.",
 			"8f2de8e6-a423-4960-8802-18c0da301989", // Infrastructure collection ID
-			verbose,
+			"",
 		)
 		if err != nil {
 			return fmt.Errorf("creating document: %w", err)
 		}
 
-		fmt.Printf("Successfully created document with ID: %s\n", doc.ID)
-		return nil
+		return printer.Print(dryRunStatus(statusEntry{
+			ID:     doc.ID,
+			Title:  doc.Title,
+			Action: "created",
+		}), "id", "title", "action", "message")
 	},
 }
 
@@ -277,18 +607,38 @@ func maskAPIKey(key string) string {
 	return key[:4] + "..." + key[len(key)-4:]
 }
 
-func normalizeURL(baseURL string) string {
-	return strings.TrimRight(baseURL, "/")
-}
-
 func init() {
-	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
+	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output (same as --log-level=debug)")
+	RootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "diagnostic log level: debug, info, warn or error")
+	RootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "diagnostic log format: text or json")
+	RootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "show the requests that would change Outline without sending them")
+	RootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "save every API request and response, with secrets scrubbed, to this directory")
+	RootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "answer API requests from interactions recorded with --record instead of the network")
+	RootCmd.PersistentFlags().StringVar(&outputFormat, "output", "table", "output format: table, tsv, json or yaml")
+	RootCmd.PersistentFlags().StringSliceVar(&outputFields, "fields", nil, "comma-separated fields to include in output")
+	RootCmd.PersistentFlags().StringVar(&outputTemplate, "format", "", "Go template applied to each result, e.g. '{{.ID}} {{.Title}}'")
+
+	pullCmd.Flags().StringVarP(&pullOut, "out", "o", "", "write the document to this path, or - for stdout (default <docID>.md)")
+	pullCmd.Flags().BoolVar(&pullSkipAttachments, "skip-attachments", false, "keep attachment links pointing at Outline instead of downloading them")
+	pullCmd.Flags().BoolVar(&pullAll, "all", false, "pull every document")
+	pullCmd.Flags().BoolVar(&pullForce, "force", false, "overwrite local changes, keeping them as <file>.orig")
+	pullCmd.Flags().IntVar(&bulkConcurrency, "concurrency", 4, "number of documents to pull at once")
+	pullCmd.Flags().StringVar(&titleLookup, "title", "", "pull the document with this exact title")
+	pushCmd.Flags().BoolVar(&pushAll, "all", false, "push every <docID>.md file in the working directory")
+	pushCmd.Flags().IntVar(&bulkConcurrency, "concurrency", 4, "number of documents to push at once")
+	pushCmd.Flags().StringVarP(&pushFile, "file", "f", "", "read the document from this path, or - for stdin (default <docID>.md)")
+	pushCmd.Flags().StringVar(&titleLookup, "title", "", "push to the document with this exact title")
+	diffCmd.Flags().StringVarP(&diffFile, "file", "f", "", "compare this file instead of <docID>.md")
+	diffCmd.Flags().StringVar(&titleLookup, "title", "", "compare the document with this exact title")
+	updateCmd.Flags().StringVar(&titleLookup, "title", "", "update the document with this exact title")
 
 	RootCmd.AddCommand(pullCmd)
 	RootCmd.AddCommand(pushCmd)
 	RootCmd.AddCommand(diffCmd)
 	RootCmd.AddCommand(debugCmd)
 	RootCmd.AddCommand(listCmd)
+	RootCmd.AddCommand(collectionsCmd)
+	RootCmd.AddCommand(searchCmd)
 	RootCmd.AddCommand(testCmd)
 	RootCmd.AddCommand(updateCmd)
 	RootCmd.AddCommand(createCmd)
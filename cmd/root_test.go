@@ -1,18 +1,27 @@
 package cmd
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"outline-cli/api"
 	"outline-cli/config"
+	"outline-cli/outlinetest"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 // Helper function to silence command output during tests
 func silenceOutput(t *testing.T) func() {
 	t.Helper()
-	null, err := os.Open(os.DevNull)
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -27,240 +36,780 @@ func silenceOutput(t *testing.T) func() {
 	}
 }
 
-// Mock config for testing
-var testConfig = &config.Config{
-	APIKey:     "test-key",
-	OutlineURL: "https://test.outline.com",
+// useFakeServer starts a fake Outline server and points the commands at it
+// until the test ends, with backups disabled and output silenced
+func useFakeServer(t *testing.T) *outlinetest.Server {
+	t.Helper()
+	srv := outlinetest.NewServer()
+	t.Cleanup(srv.Close)
+
+	cfg := srv.Config()
+	cfg.Backups.Disabled = true
+	useConfig(t, cfg)
+	return srv
 }
 
-// Mock the config loading
-func mockConfigLoader() func() {
-	original := config.LoadConfig
-	config.LoadConfig = func() (*config.Config, error) {
-		return testConfig, nil
-	}
-	return func() {
-		config.LoadConfig = original
-	}
+// useConfig makes the commands load cfg and talk to Outline over HTTP until
+// the test ends. Calling it again switches to another config.
+func useConfig(t *testing.T, cfg *config.Config) {
+	t.Helper()
+	restoreOutput := silenceOutput(t)
+	loadConfig, factory := config.LoadConfig, clientFactory
+	config.LoadConfig = func() (*config.Config, error) { return cfg, nil }
+	clientFactory = api.DefaultClientFactory
+	resetCommands()
+
+	t.Cleanup(func() {
+		resetCommands()
+		config.LoadConfig, clientFactory = loadConfig, factory
+		restoreOutput()
+	})
 }
 
 // Reset commands before each test
 func resetCommands() {
-	// Reset the commands
-	pullCmd.ResetFlags()
-	pushCmd.ResetFlags()
-	createCmd.ResetFlags()
-
-	// Reset the root command and its flags
-	RootCmd.ResetFlags()
-	RootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
-
-	// Re-add commands to root
-	RootCmd.AddCommand(pullCmd)
-	RootCmd.AddCommand(pushCmd)
-	RootCmd.AddCommand(createCmd)
+	// Restore every flag to its default so state doesn't leak between tests
+	resetFlags(RootCmd)
+	RootCmd.SetIn(nil)
+	RootCmd.SetOut(nil)
 }
 
-type mockClient struct {
-	documents map[string]*api.Document
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.PersistentFlags().VisitAll(reset)
+	cmd.Flags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
 }
 
-func newMockClient() *mockClient {
-	return &mockClient{
-		documents: make(map[string]*api.Document),
+// chdir changes the working directory until the test ends
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
 	}
+	t.Cleanup(func() {
+		if err := os.Chdir(oldWd); err != nil {
+			t.Errorf("failed to restore working directory: %v", err)
+		}
+	})
 }
 
-func (m *mockClient) GetDocument(docID string, verbose bool) (*api.Document, error) {
-	doc, exists := m.documents[docID]
-	if !exists {
-		return nil, fmt.Errorf("document not found")
+// remoteText returns the text of a document stored on srv
+func remoteText(t *testing.T, srv *outlinetest.Server, id string) string {
+	t.Helper()
+	doc, ok := srv.Document(id)
+	if !ok {
+		t.Fatalf("document %s does not exist", id)
 	}
-	return doc, nil
+	return doc.Text
 }
 
-func (m *mockClient) UpdateDocument(docID string, content string, verbose bool) error {
-	doc, exists := m.documents[docID]
-	if !exists {
-		return fmt.Errorf("document not found")
+func TestPullCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{
+		ID:    "test-id",
+		Title: "Test Document",
+		Text:  "Test content",
+	})
+
+	// Create a temporary directory for test files
+	chdir(t, t.TempDir())
+
+	// Execute pull command
+	RootCmd.SetArgs([]string{"pull", "test-id"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify file was created with correct content
+	content, err := os.ReadFile("test-id.md")
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+
+	if string(content) != "Test content" {
+		t.Errorf("expected content %q, got %q", "Test content", string(content))
 	}
-	doc.Text = content
-	return nil
 }
 
-func (m *mockClient) ListDocuments(verbose bool) ([]api.Document, error) {
-	docs := make([]api.Document, 0, len(m.documents))
-	for _, doc := range m.documents {
-		docs = append(docs, *doc)
+func TestPushCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{
+		ID:    "test-id",
+		Title: "Test Document",
+		Text:  "Original content",
+	})
+
+	// Create a temporary directory for test files
+	chdir(t, t.TempDir())
+
+	// Create test file
+	if err := os.WriteFile("test-id.md", []byte("Updated content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Execute push command
+	RootCmd.SetArgs([]string{"push", "test-id"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify document was updated
+	if text := remoteText(t, srv, "test-id"); text != "Updated content" {
+		t.Errorf("expected content %q, got %q", "Updated content", text)
 	}
-	return docs, nil
 }
 
-func (m *mockClient) CreateDocument(title string, text string, collectionId string, verbose bool) (*api.Document, error) {
-	doc := &api.Document{
-		ID:      "test-doc-id",
-		Title:   title,
-		Text:    text,
-		Version: 1,
+func TestCreateCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddCollectionWithID("8f2de8e6-a423-4960-8802-18c0da301989", "Infrastructure")
+
+	// Execute create command
+	RootCmd.SetArgs([]string{"create", "New Test Document"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Verify document was created
+	docs := srv.Documents()
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document to be created, got %d", len(docs))
+	}
+	if docs[0].Title != "New Test Document" {
+		t.Errorf("expected title %q, got %q", "New Test Document", docs[0].Title)
 	}
-	m.documents["test-doc-id"] = doc
-	return doc, nil
 }
 
-func TestPullCommand(t *testing.T) {
-	cleanup := silenceOutput(t)
-	defer cleanup()
+func TestTestAndUpdateUseClient(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-doc-id", URLID: "Xk3pQ9aB1c", Title: "Doc"})
+
+	for _, args := range [][]string{{"test"}, {"update", "https://wiki.example.com/doc/doc-Xk3pQ9aB1c"}} {
+		resetCommands()
+		RootCmd.SetArgs(args)
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", args[0], err)
+		}
+	}
+
+	calls := srv.Calls()
+	if len(calls) == 0 || calls[0] != "auth.info" || calls[len(calls)-1] != "documents.update" {
+		t.Errorf("expected auth.info then documents.update, got %v", calls)
+	}
 
-	// Reset commands
 	resetCommands()
+	RootCmd.SetArgs([]string{"update", "--title", "Doc", "test-doc-id"})
+	if err := RootCmd.Execute(); err == nil {
+		t.Error("expected update to refuse both --title and a document argument")
+	}
+}
+
+func TestPullToStdout(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{
+		ID:   "test-id",
+		Text: "Streamed content",
+	})
+
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+
+	RootCmd.SetArgs([]string{"pull", "test-id", "-o", "-"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if out.String() != "Streamed content" {
+		t.Errorf("expected stdout %q, got %q", "Streamed content", out.String())
+	}
+}
 
-	// Setup mock config and client
-	configCleanup := mockConfigLoader()
-	defer configCleanup()
+func TestPushFromStdin(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{
+		ID:   "test-id",
+		Text: "Original content",
+	})
 
-	mock := newMockClient()
-	mock.documents["test-id"] = &api.Document{
+	RootCmd.SetIn(strings.NewReader("Piped content"))
+
+	RootCmd.SetArgs([]string{"push", "test-id", "-f", "-"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text := remoteText(t, srv, "test-id"); text != "Piped content" {
+		t.Errorf("expected content %q, got %q", "Piped content", text)
+	}
+}
+
+func TestEditCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{
 		ID:    "test-id",
 		Title: "Test Document",
-		Text:  "Test content",
+		Text:  "Original content\n",
+	})
+
+	// Use sed as a non-interactive editor
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/Original/Edited/")
+
+	RootCmd.SetArgs([]string{"edit", "--title", "Test Document"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	clientFactory = func(_ *config.Config) api.Client {
-		return mock
+	if text := remoteText(t, srv, "test-id"); text != "Edited content\n" {
+		t.Errorf("expected content %q, got %q", "Edited content\n", text)
 	}
+}
 
-	// Create a temporary directory for test files
-	tmpDir, err := os.MkdirTemp("", "test")
-	if err != nil {
-		t.Fatal(err)
+func TestEditRemovesTempFile(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original content\n"})
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sed -i s/Original/Edited/")
+
+	for _, extra := range [][]string{{"--dry-run"}, nil} {
+		resetCommands()
+		RootCmd.SetArgs(append([]string{"edit", "test-id"}, extra...))
+		if err := RootCmd.Execute(); err != nil && !errors.Is(err, ErrChangesPending) {
+			t.Fatalf("edit %v: unexpected error: %v", extra, err)
+		}
+		if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+			t.Errorf("edit %v: expected the temp file removed, found %v", extra, entries)
+		}
 	}
-	defer os.RemoveAll(tmpDir)
+}
 
-	// Change to the temporary directory
-	oldWd, err := os.Getwd()
-	if err != nil {
+func TestEditChecksVersionBeforeUploading(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original content\n"})
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "logo.png"), []byte("png"), 0644); err != nil {
 		t.Fatal(err)
 	}
-
-	if err := os.Chdir(tmpDir); err != nil {
+	// The editor links a new image, then waits while the document is
+	// changed remotely
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := fmt.Sprintf("printf '![logo](logo.png)\\n' >> \"$1\"\ntouch %[1]s/editing\nwhile [ ! -e %[1]s/done ]; do sleep 0.01; done\n", tmp)
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("failed to restore working directory: %v", err)
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "sh "+editor)
+
+	go func() {
+		for {
+			if _, err := os.Stat(filepath.Join(tmp, "editing")); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
 		}
+		if err := api.DefaultClientFactory(srv.Config()).UpdateDocument("test-id", "Remote edit\n"); err != nil {
+			t.Error(err)
+		}
+		os.WriteFile(filepath.Join(tmp, "done"), nil, 0644)
 	}()
 
-	// Execute pull command
-	RootCmd.SetArgs([]string{"pull", "test-id"})
+	RootCmd.SetArgs([]string{"edit", "test-id"})
+	if err := RootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "changed remotely") {
+		t.Fatalf("expected a version conflict, got %v", err)
+	}
+	if calls := strings.Join(srv.Calls(), " "); strings.Contains(calls, "attachments.create") {
+		t.Errorf("expected nothing uploaded for a refused push, got calls %s", calls)
+	}
+}
+
+func TestPullAndPushAttachments(t *testing.T) {
+	srv := useFakeServer(t)
+	att := srv.AddAttachment("diagram.png", "image/png", []byte("png"), "test-id")
+	remote := "![diagram](" + att.URL + ")\n"
+	srv.AddDocument(api.Document{ID: "test-id", Text: remote})
+
+	dir := t.TempDir()
+	docPath := filepath.Join(dir, "test-id.md")
+
+	RootCmd.SetArgs([]string{"pull", "test-id", "-o", docPath})
 	if err := RootCmd.Execute(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// Verify file was created with correct content
-	content, err := os.ReadFile("test-id.md")
+	content, err := os.ReadFile(docPath)
 	if err != nil {
-		t.Fatalf("error reading file: %v", err)
+		t.Fatal(err)
+	}
+	if string(content) != "![diagram](assets/"+att.ID+".png)\n" {
+		t.Errorf("expected localized link, got %q", string(content))
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "assets", att.ID+".png")); err != nil || string(data) != "png" {
+		t.Errorf("expected downloaded attachment, got %q (%v)", string(data), err)
 	}
 
-	if string(content) != "Test content" {
-		t.Errorf("expected content %q, got %q", "Test content", string(content))
+	// Add a new local image and push
+	if err := os.WriteFile(filepath.Join(dir, "new.png"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edited := string(content) + "![new](new.png)\n"
+	if err := os.WriteFile(docPath, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resetCommands()
+	RootCmd.SetArgs([]string{"push", "test-id", "-f", docPath})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := remoteText(t, srv, "test-id")
+	newID := strings.TrimSuffix(strings.TrimPrefix(text, remote+"![new](/api/attachments.redirect?id="), ")\n")
+	if uploaded, ok := srv.Attachment(newID); !ok || string(uploaded.Data) != "new" {
+		t.Errorf("expected the original link kept and the new image uploaded, got %q", text)
 	}
 }
 
-func TestPushCommand(t *testing.T) {
-	cleanup := silenceOutput(t)
-	defer cleanup()
+func TestAttachCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Runbook\n"})
+
+	file := filepath.Join(t.TempDir(), "diagram.png")
+	if err := os.WriteFile(file, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	RootCmd.SetArgs([]string{"attach", "test-id", file, "--append", "image"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := remoteText(t, srv, "test-id")
+	id := strings.TrimSuffix(strings.TrimPrefix(text, "Runbook\n\n![diagram.png](/api/attachments.redirect?id="), ")\n")
+	uploaded, ok := srv.Attachment(id)
+	if !ok || string(uploaded.Data) != "png" || uploaded.DocumentID != "test-id" {
+		t.Errorf("expected the image uploaded and linked, got %q", text)
+	}
+}
+
+func TestAttachEscapesLinkText(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Runbook\n"})
+
+	file := filepath.Join(t.TempDir(), "[draft] *v2*.png")
+	if err := os.WriteFile(file, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	RootCmd.SetArgs([]string{"attach", "test-id", file, "--append", "link"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text := remoteText(t, srv, "test-id"); !strings.HasPrefix(text, `Runbook`+"\n\n"+`[\[draft\] \*v2\*.png](/api/`) {
+		t.Errorf("expected the file name escaped in the link text, got %q", text)
+	}
+}
+
+func TestAttachDryRunUsesPlaceholder(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Runbook\n"})
+	defer RootCmd.SetErr(nil)
+
+	file := filepath.Join(t.TempDir(), "diagram.png")
+	if err := os.WriteFile(file, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, stderr bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetErr(&stderr)
+	RootCmd.SetArgs([]string{"attach", "test-id", file, "--append", "image", "--dry-run", "--output", "json"})
+	if err := RootCmd.Execute(); !errors.Is(err, ErrChangesPending) {
+		t.Fatalf("expected pending changes, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "![diagram.png]((dry-run))") || strings.Contains(stderr.String(), "id=)") {
+		t.Errorf("expected the appended link to use the placeholder, got %s", stderr.String())
+	}
+	if !strings.Contains(out.String(), `"url": "(dry-run)"`) {
+		t.Errorf("expected the placeholder URL printed, got %s", out.String())
+	}
+	if text := remoteText(t, srv, "test-id"); text != "Runbook\n" {
+		t.Errorf("expected the document untouched, got %q", text)
+	}
+}
+
+func TestBulkPullAggregatesErrors(t *testing.T) {
+	srv := useFakeServer(t)
+	for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+		srv.AddDocument(api.Document{ID: id, Text: "Content of " + id})
+	}
+
+	chdir(t, t.TempDir())
+
+	RootCmd.SetArgs([]string{"pull", "doc-1", "missing", "doc-2", "doc-3", "--concurrency", "2"})
+	err := RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 of 4 documents failed") {
+		t.Fatalf("expected aggregated failure, got %v", err)
+	}
+
+	for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+		content, err := os.ReadFile(id + ".md")
+		if err != nil {
+			t.Errorf("expected %s to be pulled: %v", id, err)
+			continue
+		}
+		if string(content) != "Content of "+id {
+			t.Errorf("expected content %q, got %q", "Content of "+id, string(content))
+		}
+	}
+
+	// Push everything back concurrently
+	for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+		if err := os.WriteFile(id+".md", []byte("Updated "+id), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
 
-	// Reset commands
 	resetCommands()
+	RootCmd.SetArgs([]string{"push", "--all"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, id := range []string{"doc-1", "doc-2", "doc-3"} {
+		if text := remoteText(t, srv, id); text != "Updated "+id {
+			t.Errorf("expected %s to be pushed, got %q", id, text)
+		}
+	}
+}
 
-	// Setup mock config and client
-	configCleanup := mockConfigLoader()
-	defer configCleanup()
+func TestPushBacksUpAndRestores(t *testing.T) {
+	srv := useFakeServer(t)
+	cfg := srv.Config()
+	cfg.Backups.Dir = t.TempDir()
+	useConfig(t, cfg)
 
-	mock := newMockClient()
-	mock.documents["test-id"] = &api.Document{
-		ID:    "test-id",
-		Title: "Test Document",
-		Text:  "Original content",
+	srv.AddDocument(api.Document{ID: "test-id", URLID: "Xk3pQ9aB1c", Title: "Doc", Text: "Precious content"})
+
+	file := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(file, []byte("Clobbered"), 0644); err != nil {
+		t.Fatal(err)
 	}
 
-	clientFactory = func(_ *config.Config) api.Client {
-		return mock
+	RootCmd.SetArgs([]string{"push", "test-id", "-f", file})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text := remoteText(t, srv, "test-id"); text != "Clobbered" {
+		t.Fatalf("expected push to update the document, got %q", text)
 	}
 
-	// Create a temporary directory for test files
-	tmpDir, err := os.MkdirTemp("", "test")
-	if err != nil {
+	resetCommands()
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetArgs([]string{"backups", "show", "--title", "Doc"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Precious content" {
+		t.Errorf("expected snapshot of the remote text, got %q", out.String())
+	}
+
+	for _, ref := range [][]string{{"https://wiki.example.com/doc/doc-Xk3pQ9aB1c"}, {"--title", "Doc"}} {
+		resetCommands()
+		out.Reset()
+		RootCmd.SetOut(&out)
+		RootCmd.SetArgs(append([]string{"backups", "list", "--output", "tsv"}, ref...))
+		if err := RootCmd.Execute(); err != nil {
+			t.Fatalf("backups list %v: unexpected error: %v", ref, err)
+		}
+		if !strings.HasPrefix(out.String(), "test-id\t") {
+			t.Errorf("backups list %v: expected the document's snapshot, got %q", ref, out.String())
+		}
+	}
+
+	resetCommands()
+	RootCmd.SetArgs([]string{"backups", "restore", "https://wiki.example.com/doc/doc-Xk3pQ9aB1c"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text := remoteText(t, srv, "test-id"); text != "Precious content" {
+		t.Errorf("expected restore to re-push the snapshot, got %q", text)
+	}
+}
+
+func TestPullProtectsLocalChanges(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Remote v1"})
+
+	file := filepath.Join(t.TempDir(), "doc.md")
+	pull := func(extra ...string) error {
+		resetCommands()
+		RootCmd.SetArgs(append([]string{"pull", "test-id", "-o", file}, extra...))
+		return RootCmd.Execute()
+	}
+
+	if err := pull(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Chmod(file, 0600); err != nil {
 		t.Fatal(err)
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Change to the temporary directory
-	oldWd, err := os.Getwd()
+	// An unedited file is refreshed freely
+	updateText(t, srv, "test-id", "Remote v2")
+	if err := pull(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Local edits block the next pull
+	if err := os.WriteFile(file, []byte("Local edits"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	updateText(t, srv, "test-id", "Remote v3")
+	if err := pull(); err == nil || !strings.Contains(err.Error(), "local changes") {
+		t.Fatalf("expected pull to refuse, got %v", err)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "Local edits" {
+		t.Fatalf("expected local edits to survive, got %q", content)
+	}
+
+	if err := pull("--force"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "Remote v3" {
+		t.Errorf("expected forced pull to overwrite, got %q", content)
+	}
+	if content, _ := os.ReadFile(file + ".orig"); string(content) != "Local edits" {
+		t.Errorf("expected local edits in .orig, got %q", content)
+	}
+
+	info, err := os.Stat(file)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 to be preserved, got %v", info.Mode().Perm())
+	}
+}
 
-	if err := os.Chdir(tmpDir); err != nil {
+func TestPullRejectsPathTraversal(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "../escape", Text: "Escaped"})
+
+	parent := t.TempDir()
+	work := filepath.Join(parent, "work")
+	if err := os.Mkdir(work, 0755); err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		if err := os.Chdir(oldWd); err != nil {
-			t.Errorf("failed to restore working directory: %v", err)
-		}
-	}()
+	chdir(t, work)
 
-	// Create test file
-	if err := os.WriteFile("test-id.md", []byte("Updated content"), 0644); err != nil {
+	RootCmd.SetArgs([]string{"pull", "../escape"})
+	if err := RootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "invalid document ID") {
+		t.Fatalf("expected invalid ID error, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "escape.md")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing written outside the working directory, got %v", err)
+	}
+
+	// Links that climb out of the document's directory are never uploaded
+	if err := os.WriteFile(filepath.Join(parent, "secret.png"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv.AddDocument(api.Document{ID: "test-id"})
+	if err := os.WriteFile("test-id.md", []byte("![x](../secret.png)"), 0644); err != nil {
 		t.Fatal(err)
 	}
 
-	// Execute push command
+	resetCommands()
 	RootCmd.SetArgs([]string{"push", "test-id"})
 	if err := RootCmd.Execute(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if strings.Contains(strings.Join(srv.Calls(), " "), "attachments.create") {
+		t.Error("expected no attachments to be uploaded")
+	}
+	if text := remoteText(t, srv, "test-id"); text != "![x](../secret.png)" {
+		t.Errorf("expected link to be left unchanged, got %q", text)
+	}
+}
 
-	// Verify document was updated
-	doc := mock.documents["test-id"]
-	if doc == nil {
-		t.Fatal("document was not created")
+func TestResolveDocumentReferences(t *testing.T) {
+	srv := useFakeServer(t)
+
+	const id = "8f2de8e6-a423-4960-8802-18c0da301989"
+	srv.AddDocument(api.Document{ID: id, URLID: "Xk3pQ9aB1c", Title: "DB Failover", Text: "Runbook"})
+	srv.AddDocument(api.Document{ID: "other", Title: "Other", Text: "Unrelated"})
+	share := srv.Share(id)
+
+	refs := [][]string{
+		{"https://wiki.example.com/doc/runbook-db-failover-Xk3pQ9aB1c"},
+		{"runbook-db-failover-Xk3pQ9aB1c"},
+		{"https://wiki.example.com/s/" + share},
+		{"--title", "DB Failover"},
+	}
+	for _, ref := range refs {
+		resetCommands()
+		var out bytes.Buffer
+		RootCmd.SetOut(&out)
+		RootCmd.SetArgs(append([]string{"pull", "-o", "-"}, ref...))
+		if err := RootCmd.Execute(); err != nil {
+			t.Errorf("pull %v: unexpected error: %v", ref, err)
+			continue
+		}
+		if out.String() != "Runbook" {
+			t.Errorf("pull %v: expected %q, got %q", ref, "Runbook", out.String())
+		}
+	}
+
+	// Ambiguous titles list the candidates instead of guessing
+	srv.AddDocument(api.Document{ID: "dup", Title: "DB Failover", Text: "Copy"})
+	resetCommands()
+	RootCmd.SetIn(strings.NewReader(""))
+	RootCmd.SetArgs([]string{"pull", "-o", "-", "--title", "DB Failover"})
+	err := RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "2 documents are titled") || !strings.Contains(err.Error(), "dup") {
+		t.Errorf("expected ambiguity error listing candidates, got %v", err)
 	}
-	if doc.Text != "Updated content" {
-		t.Errorf("expected content %q, got %q", "Updated content", doc.Text)
+
+	// Titles differing only in case are suggested, never picked
+	resetCommands()
+	RootCmd.SetArgs([]string{"pull", "-o", "-", "--title", "Other Runbook"})
+	srv.AddDocument(api.Document{ID: "runbook", Title: "other runbook", Text: "Lowercase"})
+	err = RootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `did you mean "other runbook" (runbook)`) {
+		t.Errorf("expected a case-insensitive suggestion, got %v", err)
 	}
 }
 
-func TestCreateCommand(t *testing.T) {
-	cleanup := silenceOutput(t)
-	defer cleanup()
+func TestTitleLookupSearchesEveryPage(t *testing.T) {
+	srv := useFakeServer(t)
+	for i := range 120 {
+		srv.AddDocument(api.Document{Title: fmt.Sprintf("Runbook part %d", i), Text: "Other"})
+	}
+	srv.AddDocument(api.Document{ID: "runbook", Title: "Runbook", Text: "Steps"})
 
-	// Reset commands
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetArgs([]string{"pull", "-o", "-", "--title", "Runbook"})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "Steps" {
+		t.Errorf("expected the exact match from a later page, got %q", out.String())
+	}
+}
+
+func TestDiffCommand(t *testing.T) {
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "one\ntwo\n"})
+
+	file := filepath.Join(t.TempDir(), "doc.md")
+	if err := os.WriteFile(file, []byte("one\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetArgs([]string{"diff", "test-id", "-f", file})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "-two\n+2\n") {
+		t.Errorf("expected diff of the change, got %q", out.String())
+	}
+}
+
+func TestPullAndPushOverHTTP(t *testing.T) {
+	srv := useFakeServer(t)
+
+	att := srv.AddAttachment("chart.png", "image/png", []byte("chart"), "")
+	doc := srv.AddDocument(api.Document{Title: "Report", Text: "![chart](" + att.URL + ")\n"})
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "report.md")
+	RootCmd.SetArgs([]string{"pull", doc.URLID, "-o", file})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "assets/"+att.ID+".png") {
+		t.Errorf("expected localized attachment link, got %q", content)
+	}
+
+	if err := os.WriteFile(file, append(content, []byte("More text\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
 	resetCommands()
+	RootCmd.SetArgs([]string{"push", doc.ID, "-f", file})
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
 
-	// Setup mock config and client
-	configCleanup := mockConfigLoader()
-	defer configCleanup()
+	got, _ := srv.Document(doc.ID)
+	want := "![chart](" + att.URL + ")\nMore text\n"
+	if got.Text != want || got.Version != 2 {
+		t.Errorf("expected %q at version 2, got %q at %d", want, got.Text, got.Version)
+	}
+}
 
-	mock := newMockClient()
-	clientFactory = func(_ *config.Config) api.Client {
-		return mock
+func TestPullAllFetchesEveryPage(t *testing.T) {
+	srv := useFakeServer(t)
+
+	total := outlinetest.DefaultLimit * 2
+	for i := 0; i < total; i++ {
+		srv.AddDocument(api.Document{Title: fmt.Sprintf("Doc %d", i), Text: "Text"})
 	}
 
-	// Execute create command
-	RootCmd.SetArgs([]string{"create", "New Test Document"})
+	chdir(t, t.TempDir())
+
+	RootCmd.SetArgs([]string{"pull", "--all"})
 	if err := RootCmd.Execute(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	files, err := filepath.Glob("*.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != total {
+		t.Errorf("expected all %d documents pulled, got %d", total, len(files))
+	}
+}
 
-	// Verify document was created
-	doc := mock.documents["test-doc-id"]
-	if doc == nil {
-		t.Fatal("document was not created")
+func TestNewClientAppliesCacheFromConfig(t *testing.T) {
+	srv := useFakeServer(t)
+	doc := srv.AddDocument(api.Document{Title: "Runbook", Text: "Steps"})
+
+	cfg := srv.Config()
+	cfg.Backups.Disabled = true
+	cfg.CacheTTL = config.Duration(time.Minute)
+	client := newClient(cfg)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetDocument(doc.ID); err != nil {
+			t.Fatal(err)
+		}
 	}
-	if doc.Title != "New Test Document" {
-		t.Errorf("expected title %q, got %q", "New Test Document", doc.Title)
+	if n := strings.Count(strings.Join(srv.Calls(), " "), "documents.info"); n != 1 {
+		t.Errorf("expected the second fetch served from the cache, got %d calls", n)
 	}
 }
@@ -0,0 +1,972 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/assets"
+	"outline-cli/config"
+	"outline-cli/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+// manifestFile records what sync last saw, relative to the sync root
+const manifestFile = ".outline-sync.json"
+
+// syncPageSize is the number of documents or events fetched per request
+const syncPageSize = 100
+
+var syncDir string
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [collectionID...]",
+	Short: "Synchronise a local directory tree with Outline in both directions",
+	Long: `Keep collections and a local directory tree in sync in both directions.
+
+Each collection is written to its own directory, named after the collection
+with its url-id appended when two collections would share a name, with
+documents named after their titles and nested documents in a folder named
+after their parent. The
+time of the newest change seen in each collection is recorded in
+` + manifestFile + ` and the next sync asks Outline only for documents
+updated after it, using the events API when available and documents.list
+sorted by updatedAt otherwise.
+
+Sync first builds a plan and prints it, then applies it:
+  pull           the document changed remotely, or is new
+  push           the file was edited locally
+  move-local     the document was moved or renamed remotely
+  move-remote    the file was moved or renamed locally (matched by content)
+  delete-local   the document was deleted, archived or moved out remotely
+  delete-remote  the file was deleted locally
+  conflict       both sides changed, or a remote move would replace another
+                 file; nothing is done until one side is reverted
+
+New local files are left alone; use create to add them. With --dry-run the
+plan is printed to stdout and nothing is changed. Without arguments every
+collection is synced.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		client := newClient(cfg)
+		collections, err := client.ListCollections()
+		if err != nil {
+			return fmt.Errorf("listing collections: %w", err)
+		}
+		collections, err = selectCollections(collections, args)
+		if err != nil {
+			return err
+		}
+
+		root, err := workspace.NewRoot(syncDir)
+		if err != nil {
+			return err
+		}
+		manifest, err := loadManifest(root)
+		if err != nil {
+			return err
+		}
+
+		s := &syncer{client: client, root: root, manifest: manifest}
+		plan, err := s.plan(collections)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			for i := range plan {
+				plan[i].Status = "planned"
+				if plan[i].changes() {
+					pendingChanges.Add(1)
+				}
+			}
+			return printer.Print(plan, "op", "id", "path", "target", "reason")
+		}
+
+		for _, op := range plan {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%-13s %s %s\n", op.Op, op.Path, op.Target)
+		}
+
+		failed := s.apply(plan)
+		if err := manifest.save(syncDir); err != nil {
+			return err
+		}
+
+		if err := printer.Print(s.reports(collections, plan), "collection", "checked", "skipped", "updated", "pushed", "moved", "deleted", "conflicts"); err != nil {
+			return err
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d sync operations failed", failed)
+		}
+		return nil
+	},
+}
+
+// syncReport summarises the work done for one collection
+type syncReport struct {
+	CollectionID string `json:"collectionId"`
+	Collection   string `json:"collection"`
+	Checked      int    `json:"checked"`
+	Skipped      int    `json:"skipped"`
+	Updated      int    `json:"updated"`
+	Pushed       int    `json:"pushed"`
+	Moved        int    `json:"moved"`
+	Deleted      int    `json:"deleted"`
+	Conflicts    int    `json:"conflicts"`
+}
+
+// Sync operations, in the order they are applied
+const (
+	opSkip         = "skip"
+	opConflict     = "conflict"
+	opForget       = "forget"
+	opDeleteLocal  = "delete-local"
+	opMoveLocal    = "move-local"
+	opPull         = "pull"
+	opMoveRemote   = "move-remote"
+	opPush         = "push"
+	opDeleteRemote = "delete-remote"
+)
+
+var opOrder = map[string]int{
+	opSkip:         0,
+	opConflict:     1,
+	opForget:       2,
+	opDeleteLocal:  3,
+	opMoveLocal:    4,
+	opPull:         5,
+	opMoveRemote:   6,
+	opPush:         7,
+	opDeleteRemote: 8,
+}
+
+// syncOp is a single planned change to one side of the sync
+type syncOp struct {
+	Op     string `json:"op"`
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Reason string `json:"reason,omitempty"`
+	Status string `json:"status,omitempty"`
+
+	collectionID string
+	// remoteChanged is set when the remote document has unsynced changes
+	remoteChanged bool
+	// doc is the changed remote document, when it has already been fetched
+	doc *api.Document
+	// parentID and title describe the remote side of a move-remote
+	parentID string
+	title    string
+}
+
+// changes reports whether applying op alters either side
+func (op syncOp) changes() bool {
+	return op.Op != opSkip && op.Op != opConflict && op.Op != opForget
+}
+
+// collectionPlan is what the syncer learnt about one collection
+type collectionPlan struct {
+	coll    api.Collection
+	dir     string
+	layout  map[string]string
+	changed map[string]*api.Document
+	mark    time.Time
+}
+
+// syncer plans and applies the changes needed to reconcile a directory
+// tree with Outline
+type syncer struct {
+	client      api.Client
+	root        *workspace.Root
+	manifest    *syncManifest
+	collections map[string]*collectionPlan
+}
+
+// plan compares the manifest with the local tree and the remote collections
+func (s *syncer) plan(collections []api.Collection) ([]syncOp, error) {
+	s.collections = make(map[string]*collectionPlan)
+	dirs := s.collectionDirs(collections)
+	for _, coll := range collections {
+		cp, err := s.inspectCollection(coll, dirs[coll.ID])
+		if err != nil {
+			return nil, fmt.Errorf("inspecting collection %s: %w", coll.Name, err)
+		}
+		s.collections[coll.ID] = cp
+	}
+
+	untracked, err := s.untrackedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// A document moved to another synced collection is planned against the
+	// collection it is in now, not the one the manifest last saw it in
+	current := make(map[string]*collectionPlan)
+	for _, cp := range s.sortedCollections() {
+		for id := range cp.layout {
+			current[id] = cp
+		}
+	}
+
+	var plan []syncOp
+	for id, synced := range s.manifest.Documents {
+		cp := current[id]
+		if cp == nil {
+			cp = s.collections[synced.CollectionID]
+		}
+		if cp == nil {
+			// The document's collection isn't being synced this time
+			continue
+		}
+		op, err := s.planTracked(id, synced, cp, untracked)
+		if err != nil {
+			return nil, err
+		}
+		plan = append(plan, op)
+	}
+
+	for _, cp := range s.sortedCollections() {
+		for id, path := range cp.layout {
+			if _, tracked := s.manifest.Documents[id]; tracked {
+				continue
+			}
+			plan = append(plan, syncOp{
+				Op:            opPull,
+				ID:            id,
+				Path:          path,
+				Reason:        "new remote document",
+				collectionID:  cp.coll.ID,
+				remoteChanged: true,
+				doc:           cp.changed[id],
+			})
+		}
+	}
+
+	sort.SliceStable(plan, func(i, j int) bool {
+		if opOrder[plan[i].Op] != opOrder[plan[j].Op] {
+			return opOrder[plan[i].Op] < opOrder[plan[j].Op]
+		}
+		return plan[i].Path < plan[j].Path
+	})
+	return plan, nil
+}
+
+// collectionDirs names each collection's directory after the collection,
+// adding its url-id when another collection synced to the same tree has a
+// name that gives the same directory
+func (s *syncer) collectionDirs(collections []api.Collection) map[string]string {
+	names := make(map[string]string)
+	for id, state := range s.manifest.Collections {
+		names[id] = state.Name
+	}
+	for _, coll := range collections {
+		names[coll.ID] = coll.Name
+	}
+
+	// Count case-insensitively, as layoutTree does for documents
+	slugs := make(map[string]int)
+	for _, name := range names {
+		slugs[strings.ToLower(workspace.Slugify(name))]++
+	}
+
+	dirs := make(map[string]string, len(collections))
+	for _, coll := range collections {
+		dir := workspace.Slugify(coll.Name)
+		if slugs[strings.ToLower(dir)] > 1 {
+			dir += "-" + workspace.Slugify(coll.URLID)
+		}
+		dirs[coll.ID] = dir
+	}
+	return dirs
+}
+
+// sortedCollections returns the collections being synced ordered by ID, so
+// every run visits them in the same order
+func (s *syncer) sortedCollections() []*collectionPlan {
+	plans := make([]*collectionPlan, 0, len(s.collections))
+	for _, cp := range s.collections {
+		plans = append(plans, cp)
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].coll.ID < plans[j].coll.ID })
+	return plans
+}
+
+func (s *syncer) inspectCollection(coll api.Collection, dir string) (*collectionPlan, error) {
+	state := s.manifest.Collections[coll.ID]
+	if state == nil {
+		state = &syncedCollection{}
+		s.manifest.Collections[coll.ID] = state
+	}
+	state.Name = coll.Name
+
+	tree, err := s.client.CollectionDocuments(coll.ID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching document tree: %w", err)
+	}
+
+	cp := &collectionPlan{
+		coll:   coll,
+		dir:    dir,
+		layout: make(map[string]string),
+	}
+	layoutTree(cp.layout, cp.dir, tree)
+
+	cp.changed, cp.mark, err = changedDocuments(s.client, coll.ID, state.HighWaterMark)
+	if err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// planTracked decides what to do with a document that was synced before
+func (s *syncer) planTracked(id string, synced *syncedDocument, cp *collectionPlan, untracked map[string]string) (syncOp, error) {
+	op := syncOp{ID: id, Path: synced.Path, collectionID: cp.coll.ID}
+
+	doc, remoteChanged := cp.changed[id]
+	op.doc = doc
+	op.remoteChanged = remoteChanged || synced.Stale
+
+	layoutPath, inTree := cp.layout[id]
+	local, err := s.localPath(synced.Path)
+	if err != nil {
+		return op, err
+	}
+	localExists := fileExists(local)
+	dirty, err := locallyModified(local, synced)
+	if err != nil {
+		return op, err
+	}
+	blocked := false
+	if inTree && layoutPath != synced.Path {
+		if blocked, err = s.occupied(synced.Path, layoutPath); err != nil {
+			return op, err
+		}
+	}
+
+	switch {
+	case !inTree && !localExists:
+		op.Op, op.Reason = opForget, "deleted on both sides"
+
+	case !inTree && dirty:
+		op.Op, op.Reason = opConflict, "deleted remotely but edited locally"
+
+	case !inTree:
+		op.Op, op.Reason = opDeleteLocal, "deleted, archived or moved out remotely"
+
+	case !localExists:
+		if moved, ok := untracked[synced.Hash]; ok {
+			delete(untracked, synced.Hash)
+			op.Target = moved
+			if op.remoteChanged {
+				op.Op, op.Reason = opConflict, "moved locally but edited remotely"
+				break
+			}
+			op.Op, op.Reason = opMoveRemote, "moved or renamed locally"
+			if err := s.resolveRemoteMove(&op, synced); err != nil {
+				op.Op, op.Reason = opConflict, err.Error()
+			}
+			break
+		}
+		if op.remoteChanged {
+			op.Op, op.Reason = opConflict, "deleted locally but edited remotely"
+			break
+		}
+		op.Op, op.Reason = opDeleteRemote, "deleted locally"
+
+	case layoutPath != synced.Path && dirty:
+		op.Target = layoutPath
+		op.Op, op.Reason = opConflict, "moved remotely but edited locally"
+
+	case layoutPath != synced.Path && blocked:
+		op.Target = layoutPath
+		op.Op, op.Reason = opConflict, "moved remotely but another file is in the way"
+
+	case layoutPath != synced.Path:
+		op.Target = layoutPath
+		op.Op, op.Reason = opMoveLocal, "moved or renamed remotely"
+
+	case dirty && op.remoteChanged:
+		op.Op, op.Reason = opConflict, "edited on both sides"
+
+	case dirty:
+		op.Op, op.Reason = opPush, "edited locally"
+
+	case op.remoteChanged:
+		op.Op, op.Reason = opPull, "edited remotely"
+
+	default:
+		op.Op = opSkip
+	}
+	return op, nil
+}
+
+// resolveRemoteMove works out the collection, parent and title that a
+// locally moved file implies for its document
+func (s *syncer) resolveRemoteMove(op *syncOp, synced *syncedDocument) error {
+	op.title = renamedTitle(op.ID, synced.Title, op.Target)
+
+	dir := path.Dir(op.Target)
+	for _, cp := range s.sortedCollections() {
+		if dir == cp.dir {
+			op.collectionID, op.parentID = cp.coll.ID, ""
+			return nil
+		}
+		if strings.HasPrefix(dir, cp.dir+"/") {
+			parent := s.documentAt(cp, dir+".md")
+			if parent == "" {
+				return fmt.Errorf("no document matches folder %s", dir)
+			}
+			op.collectionID, op.parentID = cp.coll.ID, parent
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not inside a synced collection", op.Target)
+}
+
+// occupied reports whether moving the file at from to to would replace
+// another file
+func (s *syncer) occupied(from string, to string) (bool, error) {
+	dst, err := s.localPath(to)
+	if err != nil {
+		return false, err
+	}
+	src, err := s.localPath(from)
+	if err != nil {
+		return false, err
+	}
+	target, err := os.Stat(dst)
+	if err != nil {
+		return false, nil
+	}
+	source, err := os.Stat(src)
+	// On case-insensitive file systems a rename that only changes case
+	// finds the file itself
+	return err != nil || !os.SameFile(source, target), nil
+}
+
+// renamedTitle returns the title implied by a file moved to target. The
+// remote title is kept unless the file name no longer matches it, as the
+// name can't carry the title's case and punctuation.
+func renamedTitle(id string, title string, target string) string {
+	name := strings.TrimSuffix(path.Base(target), ".md")
+	slug := workspace.Slugify(title)
+	if name == slug || name == slug+"-"+workspace.Slugify(shortID(id)) {
+		return title
+	}
+	return strings.ReplaceAll(name, "-", " ")
+}
+
+// documentAt returns the ID of the document synced to rel, if any
+func (s *syncer) documentAt(cp *collectionPlan, rel string) string {
+	for id, d := range s.manifest.Documents {
+		if d.Path == rel {
+			return id
+		}
+	}
+	for id, p := range cp.layout {
+		if p == rel {
+			return id
+		}
+	}
+	return ""
+}
+
+// untrackedFiles indexes Markdown files below the synced collections that
+// the manifest doesn't know about, by content hash
+func (s *syncer) untrackedFiles() (map[string]string, error) {
+	tracked := make(map[string]bool, len(s.manifest.Documents))
+	for _, d := range s.manifest.Documents {
+		tracked[d.Path] = true
+	}
+
+	// Attachments are downloaded into the assets directory next to each
+	// document, which holds no documents of its own
+	assetDirs := make(map[string]bool)
+	for rel := range tracked {
+		assetDirs[path.Join(path.Dir(rel), assets.Dir)] = true
+	}
+	for _, cp := range s.sortedCollections() {
+		for _, rel := range cp.layout {
+			assetDirs[path.Join(path.Dir(rel), assets.Dir)] = true
+		}
+	}
+
+	untracked := make(map[string]string)
+	for _, cp := range s.sortedCollections() {
+		dir, err := s.localPath(cp.dir)
+		if err != nil {
+			return nil, err
+		}
+		err = filepath.WalkDir(dir, func(name string, d os.DirEntry, err error) error {
+			if errors.Is(err, os.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(s.root.Dir(), name)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if d.IsDir() && assetDirs[rel] {
+				return filepath.SkipDir
+			}
+			if d.IsDir() || filepath.Ext(name) != ".md" || tracked[rel] {
+				return nil
+			}
+
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			untracked[contentHash(data)] = rel
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", dir, err)
+		}
+	}
+	return untracked, nil
+}
+
+// apply carries out the plan, recording each operation's status and
+// returning how many failed
+func (s *syncer) apply(plan []syncOp) int {
+	var failed int
+	for i := range plan {
+		op := &plan[i]
+		if op.Op == opSkip || op.Op == opConflict {
+			if op.Op == opConflict && op.remoteChanged {
+				if synced := s.manifest.Documents[op.ID]; synced != nil {
+					synced.Stale = true
+				}
+			}
+			continue
+		}
+
+		if err := s.applyOp(op); err != nil {
+			logger.Error("sync operation failed", "op", op.Op, "id", op.ID, "error", err)
+			op.Status = "failed"
+			op.Reason = err.Error()
+			if synced := s.manifest.Documents[op.ID]; synced != nil && op.remoteChanged {
+				synced.Stale = true
+			}
+			failed++
+			continue
+		}
+		op.Status = "done"
+	}
+
+	for _, cp := range s.sortedCollections() {
+		s.manifest.Collections[cp.coll.ID].HighWaterMark = cp.mark
+	}
+	return failed
+}
+
+func (s *syncer) applyOp(op *syncOp) error {
+	switch op.Op {
+	case opForget:
+		delete(s.manifest.Documents, op.ID)
+
+	case opDeleteLocal:
+		local, err := s.localPath(op.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(local); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		delete(s.manifest.Documents, op.ID)
+
+	case opDeleteRemote:
+		if err := s.client.DeleteDocument(op.ID); err != nil {
+			return fmt.Errorf("deleting document: %w", err)
+		}
+		delete(s.manifest.Documents, op.ID)
+
+	case opMoveLocal:
+		from, err := s.localPath(op.Path)
+		if err != nil {
+			return err
+		}
+		to, err := s.localPath(op.Target)
+		if err != nil {
+			return err
+		}
+		if blocked, err := s.occupied(op.Path, op.Target); err != nil || blocked {
+			return errors.Join(err, fmt.Errorf("refusing to overwrite %s", op.Target))
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
+		}
+		s.manifest.Documents[op.ID].Path = op.Target
+		s.manifest.Documents[op.ID].CollectionID = op.collectionID
+		if op.remoteChanged {
+			return s.pull(op.ID, op.Target, op.collectionID, op.doc)
+		}
+
+	case opMoveRemote:
+		synced := s.manifest.Documents[op.ID]
+		if op.collectionID != synced.CollectionID || path.Dir(op.Target) != path.Dir(synced.Path) {
+			if err := s.client.MoveDocument(op.ID, op.collectionID, op.parentID); err != nil {
+				return fmt.Errorf("moving document: %w", err)
+			}
+		}
+		if op.title != synced.Title {
+			if err := s.client.RenameDocument(op.ID, op.title); err != nil {
+				return fmt.Errorf("renaming document: %w", err)
+			}
+		}
+		synced.Path = op.Target
+		synced.Title = op.title
+		synced.CollectionID = op.collectionID
+
+	case opPull:
+		return s.pull(op.ID, op.Path, op.collectionID, op.doc)
+
+	case opPush:
+		local, err := s.localPath(op.Path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(local)
+		if err != nil {
+			return err
+		}
+		text, err := uploadAttachments(s.client, string(content), filepath.Dir(local), op.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.client.UpdateDocument(op.ID, text); err != nil {
+			return fmt.Errorf("updating document: %w", err)
+		}
+		s.manifest.Documents[op.ID].Hash = contentHash(content)
+	}
+	return nil
+}
+
+// pull writes the remote document to rel and records it in the manifest
+func (s *syncer) pull(id string, rel string, collectionID string, doc *api.Document) error {
+	filename, err := s.localPath(rel)
+	if err != nil {
+		return err
+	}
+	if _, tracked := s.manifest.Documents[id]; !tracked && fileExists(filename) {
+		return fmt.Errorf("refusing to overwrite untracked file %s", rel)
+	}
+
+	if doc == nil {
+		doc, err = s.client.GetDocument(id)
+		if err != nil {
+			return fmt.Errorf("fetching document: %w", err)
+		}
+	}
+
+	hash, err := writeSyncedFile(s.client, filename, doc.Text)
+	if err != nil {
+		return err
+	}
+	s.manifest.Documents[id] = &syncedDocument{
+		CollectionID: collectionID,
+		Title:        doc.Title,
+		Path:         rel,
+		Hash:         hash,
+		UpdatedAt:    doc.UpdatedAt,
+	}
+	if cp := s.collections[collectionID]; cp != nil && doc.UpdatedAt.After(cp.mark) {
+		cp.mark = doc.UpdatedAt
+	}
+	return nil
+}
+
+// reports tallies the plan per collection
+func (s *syncer) reports(collections []api.Collection, plan []syncOp) []syncReport {
+	byID := make(map[string]*syncReport, len(collections))
+	reports := make([]syncReport, len(collections))
+	for i, coll := range collections {
+		reports[i] = syncReport{CollectionID: coll.ID, Collection: coll.Name}
+		byID[coll.ID] = &reports[i]
+	}
+
+	for _, op := range plan {
+		r := byID[op.collectionID]
+		if r == nil {
+			continue
+		}
+		r.Checked++
+		if op.Status == "failed" {
+			continue
+		}
+		switch op.Op {
+		case opSkip, opForget:
+			r.Skipped++
+		case opConflict:
+			r.Conflicts++
+		case opPull:
+			r.Updated++
+		case opPush:
+			r.Pushed++
+		case opMoveLocal, opMoveRemote:
+			r.Moved++
+		case opDeleteLocal, opDeleteRemote:
+			r.Deleted++
+		}
+	}
+	return reports
+}
+
+// localPath resolves a path relative to the sync root, refusing any that
+// would lead outside it, such as through a symlinked directory
+func (s *syncer) localPath(rel string) (string, error) {
+	return s.root.Resolve(rel)
+}
+
+// syncManifest is the persisted state of a synced directory tree
+type syncManifest struct {
+	Collections map[string]*syncedCollection `json:"collections"`
+	Documents   map[string]*syncedDocument   `json:"documents"`
+}
+
+type syncedCollection struct {
+	Name string `json:"name"`
+	// HighWaterMark is the newest change seen in the collection
+	HighWaterMark time.Time `json:"highWaterMark"`
+}
+
+type syncedDocument struct {
+	CollectionID string `json:"collectionId"`
+	Title        string `json:"title"`
+	// Path is slash-separated and relative to the sync root
+	Path string `json:"path"`
+	// Hash is the SHA-256 of the file content as last written by sync
+	Hash      string    `json:"hash"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Stale marks a document changed remotely but not yet written locally
+	Stale bool `json:"stale,omitempty"`
+}
+
+// loadManifest reads the manifest below root, rejecting any recorded path
+// that would lead outside it
+func loadManifest(root *workspace.Root) (*syncManifest, error) {
+	m := &syncManifest{
+		Collections: make(map[string]*syncedCollection),
+		Documents:   make(map[string]*syncedDocument),
+	}
+
+	data, err := os.ReadFile(filepath.Join(root.Dir(), manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading sync manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing sync manifest: %w", err)
+	}
+	for id, doc := range m.Documents {
+		if _, err := root.Resolve(doc.Path); err != nil {
+			return nil, fmt.Errorf("sync manifest entry %s: %w", id, err)
+		}
+	}
+	return m, nil
+}
+
+func (m *syncManifest) save(root string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding sync manifest: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(root, manifestFile), data, 0644); err != nil {
+		return fmt.Errorf("writing sync manifest: %w", err)
+	}
+	return nil
+}
+
+// selectCollections narrows collections to those named by ID or name
+func selectCollections(collections []api.Collection, wanted []string) ([]api.Collection, error) {
+	if len(wanted) == 0 {
+		return collections, nil
+	}
+
+	var selected []api.Collection
+	for _, w := range wanted {
+		found := false
+		for _, c := range collections {
+			if c.ID == w || c.URLID == w || strings.EqualFold(c.Name, w) {
+				selected = append(selected, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("collection %q not found", w)
+		}
+	}
+	return selected, nil
+}
+
+// changedDocuments returns the documents in a collection changed after
+// since, and the newest change time seen. Map values are nil when only
+// the ID of a changed document is known.
+func changedDocuments(client api.Client, collectionID string, since time.Time) (map[string]*api.Document, time.Time, error) {
+	if !since.IsZero() {
+		changed, mark, err := changedFromEvents(client, collectionID, since)
+		if err == nil {
+			return changed, mark, nil
+		}
+		logger.Info("events API unavailable, falling back to documents.list", "error", err)
+	}
+
+	changed := make(map[string]*api.Document)
+	mark := since
+	for offset := 0; ; offset += syncPageSize {
+		docs, err := client.QueryDocuments(api.ListOptions{
+			CollectionID: collectionID,
+			Sort:         "updatedAt",
+			Direction:    "DESC",
+			Offset:       offset,
+			Limit:        syncPageSize,
+		})
+		if err != nil {
+			return nil, since, fmt.Errorf("listing documents: %w", err)
+		}
+
+		for i := range docs {
+			if !docs[i].UpdatedAt.After(since) {
+				return changed, mark, nil
+			}
+			changed[docs[i].ID] = &docs[i]
+			if docs[i].UpdatedAt.After(mark) {
+				mark = docs[i].UpdatedAt
+			}
+		}
+		if len(docs) < syncPageSize {
+			return changed, mark, nil
+		}
+	}
+}
+
+func changedFromEvents(client api.Client, collectionID string, since time.Time) (map[string]*api.Document, time.Time, error) {
+	changed := make(map[string]*api.Document)
+	mark := since
+	for offset := 0; ; offset += syncPageSize {
+		events, err := client.ListEvents(api.ListOptions{
+			CollectionID: collectionID,
+			Sort:         "createdAt",
+			Direction:    "DESC",
+			Offset:       offset,
+			Limit:        syncPageSize,
+		})
+		if err != nil {
+			return nil, since, err
+		}
+
+		for _, e := range events {
+			if !e.CreatedAt.After(since) {
+				return changed, mark, nil
+			}
+			if e.DocumentID != "" && strings.HasPrefix(e.Name, "documents.") {
+				changed[e.DocumentID] = nil
+			}
+			if e.CreatedAt.After(mark) {
+				mark = e.CreatedAt
+			}
+		}
+		if len(events) < syncPageSize {
+			return changed, mark, nil
+		}
+	}
+}
+
+// layoutTree assigns each document in nodes a path below dir. Documents
+// with children get a folder of the same name for them.
+func layoutTree(layout map[string]string, dir string, nodes []api.NavigationNode) {
+	// Count case-insensitively so siblings can't collide on file systems
+	// that ignore case
+	names := make(map[string]int)
+	for _, n := range nodes {
+		names[strings.ToLower(workspace.Slugify(n.Title))]++
+	}
+
+	for _, n := range nodes {
+		name := workspace.Slugify(n.Title)
+		if names[strings.ToLower(name)] > 1 {
+			// Disambiguate siblings that share a title
+			name += "-" + workspace.Slugify(shortID(n.ID))
+		}
+		layout[n.ID] = dir + "/" + name + ".md"
+		layoutTree(layout, dir+"/"+name, n.Children)
+	}
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// writeSyncedFile writes text, with attachments localized, to filename
+// and returns the hash of what was written
+func writeSyncedFile(client api.Client, filename string, text string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return "", fmt.Errorf("creating directory: %w", err)
+	}
+
+	text, err := downloadAttachments(client, text, filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(filename, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+	return contentHash([]byte(text)), nil
+}
+
+// locallyModified reports whether the synced file at filename was edited
+// since sync wrote it
+func locallyModified(filename string, doc *syncedDocument) (bool, error) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", doc.Path, err)
+	}
+	return contentHash(data) != doc.Hash, nil
+}
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func init() {
+	syncCmd.Flags().StringVar(&syncDir, "dir", ".", "root directory of the synced tree")
+
+	RootCmd.AddCommand(syncCmd)
+}
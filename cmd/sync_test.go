@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"outline-cli/api"
+	"outline-cli/workspace"
+)
+
+func runSync(t *testing.T, dir string, args ...string) []syncReport {
+	t.Helper()
+	resetCommands()
+
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetArgs(append([]string{"sync", "--dir", dir, "--output", "json"}, args...))
+	if err := RootCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var reports []syncReport
+	if err := json.Unmarshal(out.Bytes(), &reports); err != nil {
+		t.Fatalf("decoding sync report: %v\n%s", err, out.String())
+	}
+	return reports
+}
+
+func TestSyncFetchesOnlyChangedDocuments(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Database", Text: "db", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-2", Title: "Failover Plan", Text: "failover", CollectionID: coll.ID, ParentDocumentID: "doc-1"})
+	srv.AddDocument(api.Document{ID: "doc-3", Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	reports := runSync(t, dir)
+	if len(reports) != 1 || reports[0].Checked != 3 || reports[0].Updated != 3 {
+		t.Fatalf("expected 3 documents checked and updated, got %+v", reports)
+	}
+
+	failover := filepath.Join(dir, "Runbooks", "Database", "Failover-Plan.md")
+	if content, err := os.ReadFile(failover); err != nil || string(content) != "failover" {
+		t.Fatalf("expected nested document at %s, got %q (%v)", failover, string(content), err)
+	}
+
+	// Change two documents remotely, one of which also has local edits
+	updateText(t, srv, "doc-2", "failover v2")
+	updateText(t, srv, "doc-3", "deploy v2")
+
+	deploy := filepath.Join(dir, "Runbooks", "Deploy.md")
+	if err := os.WriteFile(deploy, []byte("local deploy edits"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reports = runSync(t, dir)
+	if reports[0].Checked != 3 || reports[0].Updated != 1 || reports[0].Skipped != 1 || reports[0].Conflicts != 1 {
+		t.Errorf("expected 3 checked, 1 updated, 1 skipped, 1 conflict, got %+v", reports[0])
+	}
+
+	if content, _ := os.ReadFile(failover); string(content) != "failover v2" {
+		t.Errorf("expected remote change to be pulled, got %q", string(content))
+	}
+	if content, _ := os.ReadFile(deploy); string(content) != "local deploy edits" {
+		t.Errorf("expected local edits to be kept, got %q", string(content))
+	}
+}
+
+func TestSyncPropagatesDeletesAndMoves(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Database", Text: "db", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-2", Title: "Backups", Text: "backups", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-3", Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-4", Title: "Legacy", Text: "legacy", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	runbooks := filepath.Join(dir, "Runbooks")
+
+	// Locally: delete Deploy and move Backups under Database with a new name
+	if err := os.Remove(filepath.Join(runbooks, "Deploy.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(runbooks, "Database"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(runbooks, "Backups.md"), filepath.Join(runbooks, "Database", "Nightly-Backups.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remotely: delete Legacy and rename Database
+	if err := api.DefaultClientFactory(srv.Config()).DeleteDocument("doc-4"); err != nil {
+		t.Fatal(err)
+	}
+	renameDocument(t, srv, "doc-1", "Primary Database")
+
+	// A dry run plans without changing anything
+	resetCommands()
+	var out bytes.Buffer
+	RootCmd.SetOut(&out)
+	RootCmd.SetArgs([]string{"sync", "--dir", dir, "--dry-run", "--output", "json"})
+	if err := RootCmd.Execute(); !errors.Is(err, ErrChangesPending) {
+		t.Fatalf("expected pending changes, got %v", err)
+	}
+	var plan []syncOp
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+	ops := make(map[string]string)
+	for _, op := range plan {
+		ops[op.ID] = op.Op
+	}
+	want := map[string]string{"doc-1": opMoveLocal, "doc-2": opMoveRemote, "doc-3": opDeleteRemote, "doc-4": opDeleteLocal}
+	for id, op := range want {
+		if ops[id] != op {
+			t.Errorf("expected %s to plan %s, got %s", id, op, ops[id])
+		}
+	}
+	if _, exists := srv.Document("doc-3"); !exists {
+		t.Fatal("dry run deleted a remote document")
+	}
+
+	runSync(t, dir)
+
+	if _, exists := srv.Document("doc-3"); exists {
+		t.Error("expected locally deleted document to be deleted remotely")
+	}
+	if fileExists(filepath.Join(runbooks, "Legacy.md")) {
+		t.Error("expected remotely deleted document to be deleted locally")
+	}
+	if !fileExists(filepath.Join(runbooks, "Primary-Database.md")) {
+		t.Error("expected remotely renamed document to be moved locally")
+	}
+	if doc, _ := srv.Document("doc-2"); doc.ParentDocumentID != "doc-1" || doc.Title != "Nightly Backups" {
+		t.Errorf("expected locally moved document to be moved remotely, got parent %q title %q", doc.ParentDocumentID, doc.Title)
+	}
+}
+
+func TestSyncMovesDocumentsBetweenCollections(t *testing.T) {
+	srv := useFakeServer(t)
+	runbooks := srv.AddCollection("Runbooks")
+	archive := srv.AddCollection("Archive")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Deploy", Text: "deploy", CollectionID: runbooks.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	if err := api.DefaultClientFactory(srv.Config()).MoveDocument("doc-1", archive.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := runSync(t, dir)
+	if reports[1].Moved != 1 || reports[0].Deleted != 0 {
+		t.Errorf("expected the document moved rather than deleted, got %+v", reports)
+	}
+	if fileExists(filepath.Join(dir, "Runbooks", "Deploy.md")) || !fileExists(filepath.Join(dir, "Archive", "Deploy.md")) {
+		t.Error("expected the file moved to the new collection's directory")
+	}
+
+	// The manifest follows the move, so the next sync has nothing to do
+	reports = runSync(t, dir)
+	if reports[0].Moved != 0 || reports[1].Moved != 0 || reports[1].Deleted != 0 {
+		t.Errorf("expected the moved document to be up to date, got %+v", reports)
+	}
+}
+
+func TestSyncKeepsRemoteTitleOnLocalMove(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Database", Text: "db", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-2", Title: "What's new?", Text: "news", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-3", Title: "What's new?", Text: "more news", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	// Moved where it has no namesake, the file loses its disambiguating
+	// suffix but is not renamed
+	runbooks := filepath.Join(dir, "Runbooks")
+	if err := os.MkdirAll(filepath.Join(runbooks, "Database"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(runbooks, "Whats-new-doc-2.md"), filepath.Join(runbooks, "Database", "Whats-new.md")); err != nil {
+		t.Fatal(err)
+	}
+	runSync(t, dir)
+
+	if doc, _ := srv.Document("doc-2"); doc.ParentDocumentID != "doc-1" || doc.Title != "What's new?" {
+		t.Errorf("expected the document moved with its title kept, got parent %q title %q", doc.ParentDocumentID, doc.Title)
+	}
+}
+
+func TestSyncScansDirectoriesNamedAssets(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("assets")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+	srv.AddDocument(api.Document{ID: "doc-2", Title: "Rollback", Text: "rollback", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	// Only the attachment folder next to a document is skipped, not a
+	// collection folder that happens to share its name
+	if err := os.Rename(filepath.Join(dir, "assets", "Deploy.md"), filepath.Join(dir, "assets", "Release.md")); err != nil {
+		t.Fatal(err)
+	}
+	reports := runSync(t, dir)
+	if len(reports) != 1 || reports[0].Moved != 1 {
+		t.Fatalf("expected the local rename to be pushed, got %+v", reports)
+	}
+	if doc, _ := srv.Document("doc-1"); doc.Title != "Release" {
+		t.Errorf("expected the document renamed, got %q", doc.Title)
+	}
+}
+
+func TestSyncRefusesToMoveOverAnotherFile(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	release := filepath.Join(dir, "Runbooks", "Release.md")
+	if err := os.WriteFile(release, []byte("local notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renameDocument(t, srv, "doc-1", "Release")
+
+	reports := runSync(t, dir)
+	if reports[0].Conflicts != 1 || reports[0].Moved != 0 {
+		t.Errorf("expected a conflict rather than a move, got %+v", reports[0])
+	}
+	if content, _ := os.ReadFile(release); string(content) != "local notes" {
+		t.Errorf("expected the file in the way to be kept, got %q", content)
+	}
+	if !fileExists(filepath.Join(dir, "Runbooks", "Deploy.md")) {
+		t.Error("expected the synced file to stay where it was")
+	}
+}
+
+func TestSyncSeparatesCollectionsWithTheSameName(t *testing.T) {
+	srv := useFakeServer(t)
+	first := srv.AddCollection("Runbooks")
+	second := srv.AddCollection("runbooks")
+	srv.AddDocument(api.Document{Title: "Deploy", Text: "first", CollectionID: first.ID})
+	srv.AddDocument(api.Document{Title: "Deploy", Text: "second", CollectionID: second.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+
+	for coll, want := range map[api.Collection]string{first: "first", second: "second"} {
+		file := filepath.Join(dir, coll.Name+"-"+coll.URLID, "Deploy.md")
+		if content, err := os.ReadFile(file); err != nil || string(content) != want {
+			t.Errorf("expected %q at %s, got %q (%v)", want, file, string(content), err)
+		}
+	}
+
+	// Syncing one of them alone keeps its directory
+	reports := runSync(t, dir, first.ID)
+	if len(reports) != 1 || reports[0].Moved != 0 || reports[0].Deleted != 0 {
+		t.Errorf("expected the document left in place, got %+v", reports)
+	}
+}
+
+func TestSyncFollowsEvents(t *testing.T) {
+	srv := useFakeServer(t)
+
+	coll := srv.AddCollection("Runbooks")
+	database := srv.AddDocument(api.Document{Title: "Database", Text: "db", CollectionID: coll.ID})
+	deploy := srv.AddDocument(api.Document{Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+	legacy := srv.AddDocument(api.Document{Title: "Legacy", Text: "legacy", CollectionID: coll.ID})
+	backups := srv.AddDocument(api.Document{Title: "Backups", Text: "backups", CollectionID: coll.ID})
+
+	dir := t.TempDir()
+	runSync(t, dir)
+	synced := len(srv.Calls())
+
+	client := api.DefaultClientFactory(srv.Config())
+	if err := client.DeleteDocument(deploy.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call("documents.archive", map[string]string{"id": legacy.ID}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MoveDocument(backups.ID, coll.ID, database.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := runSync(t, dir)
+	if reports[0].Deleted != 2 || reports[0].Moved != 1 {
+		t.Errorf("expected 2 deleted and 1 moved, got %+v", reports[0])
+	}
+	calls := strings.Join(srv.Calls()[synced:], " ")
+	if !strings.Contains(calls, "events.list") || strings.Contains(calls, "documents.list") {
+		t.Errorf("expected changes to come from events, got calls %s", calls)
+	}
+
+	runbooks := filepath.Join(dir, "Runbooks")
+	if fileExists(filepath.Join(runbooks, "Deploy.md")) || fileExists(filepath.Join(runbooks, "Legacy.md")) {
+		t.Error("expected deleted and archived documents removed locally")
+	}
+	if content, err := os.ReadFile(filepath.Join(runbooks, "Database", "Backups.md")); err != nil || string(content) != "backups" {
+		t.Errorf("expected the moved document under its new parent, got %q (%v)", content, err)
+	}
+}
+
+func TestSyncStaysInsideRoot(t *testing.T) {
+	srv := useFakeServer(t)
+	coll := srv.AddCollection("Runbooks")
+	srv.AddDocument(api.Document{ID: "doc-1", Title: "Deploy", Text: "deploy", CollectionID: coll.ID})
+
+	dir, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "Runbooks")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	resetCommands()
+	RootCmd.SetOut(&bytes.Buffer{})
+	RootCmd.SetArgs([]string{"sync", "--dir", dir})
+	if err := RootCmd.Execute(); !errors.Is(err, workspace.ErrOutsideRoot) {
+		t.Errorf("expected sync to refuse the symlinked directory, got %v", err)
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+		t.Errorf("expected nothing written outside the root, got %v", entries)
+	}
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/config"
+	"outline-cli/workspace"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+var watchDebounce time.Duration
+var watchPollInterval time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [paths...]",
+	Short: "Push local saves and pull remote edits continuously",
+	Long: `Watch pulled Markdown files and keep them in sync with Outline.
+
+Each path is a <docID>.md file or a directory containing such files; the
+default is the working directory. Saves are debounced and pushed with
+documents.update, unless the document was changed remotely since it was last
+synced. Remote documents are polled and newer versions are pulled into files
+that have no unsaved local changes. Status lines are written to stderr. Press
+Ctrl-C to stop; saves still waiting out the debounce are pushed first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := watchedFiles(args)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return errors.New("no <docID>.md files to watch")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		w := &docWatcher{
+			client: newClient(cfg),
+			status: cmd.ErrOrStderr(),
+			files:  make(map[string]*watchedFile),
+		}
+		return w.run(ctx, files)
+	},
+}
+
+// watchedFile tracks the last content known to match the remote document
+type watchedFile struct {
+	docID     string
+	synced    string
+	version   int
+	updatedAt time.Time
+}
+
+// docWatcher pushes local saves and pulls remote edits for a set of files
+type docWatcher struct {
+	client api.Client
+	status io.Writer
+	files  map[string]*watchedFile
+}
+
+func (w *docWatcher) run(ctx context.Context, paths []string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("starting file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch directories rather than files so editors that save by
+	// renaming a temp file over the original are still noticed
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		if err := w.track(path); err != nil {
+			var apiErr *api.Error
+			if !errors.Is(err, workspace.ErrInvalidID) && !(errors.As(err, &apiErr) && apiErr.NotFound()) {
+				return err
+			}
+			// Other Markdown files, such as a README, live alongside
+			// pulled documents
+			logger.Warn("skipping file not named after a document", "path", path)
+			continue
+		}
+		dirs[filepath.Dir(path)] = true
+	}
+	if len(w.files) == 0 {
+		return errors.New("no <docID>.md files to watch")
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watching %s: %w", dir, err)
+		}
+	}
+	w.printf("watching %d file(s); press Ctrl-C to stop", len(w.files))
+
+	changes := make(chan string)
+	timers := make(map[string]*time.Timer)
+	poll := time.NewTicker(watchPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Push saves still waiting out the debounce rather than
+			// dropping them
+			for path, t := range timers {
+				t.Stop()
+				w.pushLocal(path)
+			}
+			w.printf("stopped")
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			path := filepath.Clean(event.Name)
+			if _, tracked := w.files[path]; !tracked || !event.Has(fsnotify.Write|fsnotify.Create) {
+				continue
+			}
+			if t, pending := timers[path]; pending {
+				t.Reset(watchDebounce)
+				continue
+			}
+			timers[path] = time.AfterFunc(watchDebounce, func() {
+				select {
+				case changes <- path:
+				case <-ctx.Done():
+				}
+			})
+
+		case path := <-changes:
+			delete(timers, path)
+			w.pushLocal(path)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.printf("watch error: %v", err)
+
+		case <-poll.C:
+			w.pullRemote()
+		}
+	}
+}
+
+// track records the remote state of path as its synced baseline
+func (w *docWatcher) track(path string) error {
+	docID, err := workspace.IDFromFile(path)
+	if err != nil {
+		return err
+	}
+	doc, err := w.client.GetDocument(docID)
+	if err != nil {
+		return fmt.Errorf("fetching document %s: %w", docID, err)
+	}
+
+	text, err := downloadAttachments(w.client, doc.Text, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	w.files[path] = &watchedFile{
+		docID:     docID,
+		synced:    text,
+		version:   doc.Version,
+		updatedAt: doc.UpdatedAt,
+	}
+	return nil
+}
+
+// pushLocal uploads path if its content differs from the synced baseline
+func (w *docWatcher) pushLocal(path string) {
+	f := w.files[path]
+	content, err := os.ReadFile(path)
+	if err != nil {
+		w.printf("error reading %s: %v", path, err)
+		return
+	}
+	if string(content) == f.synced {
+		return
+	}
+
+	// Don't overwrite edits made elsewhere since the last sync
+	current, err := w.client.GetDocument(f.docID)
+	if err != nil {
+		w.printf("error checking remote version of %s: %v", path, err)
+		return
+	}
+	if current.Version != f.version {
+		w.printf("conflict: %s was changed remotely (version %d -> %d) since it was last synced; not pushing", path, f.version, current.Version)
+		return
+	}
+
+	text, err := uploadAttachments(w.client, string(content), filepath.Dir(path), f.docID)
+	if err != nil {
+		w.printf("error uploading attachments for %s: %v", path, err)
+		return
+	}
+
+	if err := w.client.UpdateDocument(f.docID, text); err != nil {
+		w.printf("error pushing %s: %v", path, err)
+		return
+	}
+	if !dryRun {
+		f.synced = string(content)
+		if err := recordPulled(path, content); err != nil {
+			w.printf("error recording %s: %v", path, err)
+		}
+	}
+
+	// Refresh the version so the next poll doesn't mistake our own push
+	// for a remote edit
+	if doc, err := w.client.GetDocument(f.docID); err == nil {
+		f.version = doc.Version
+		f.updatedAt = doc.UpdatedAt
+	}
+	w.printf("pushed %s", path)
+}
+
+// pullRemote writes newer remote versions into files without local edits
+func (w *docWatcher) pullRemote() {
+	for path, f := range w.files {
+		doc, err := w.client.GetDocument(f.docID)
+		if err != nil {
+			w.printf("error polling %s: %v", f.docID, err)
+			continue
+		}
+		if doc.Version == f.version && doc.UpdatedAt.Equal(f.updatedAt) {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.printf("error reading %s: %v", path, err)
+			continue
+		}
+		if err == nil && string(content) != f.synced {
+			w.printf("skipped %s: changed remotely but has unsaved local edits", path)
+			continue
+		}
+
+		text, err := downloadAttachments(w.client, doc.Text, filepath.Dir(path))
+		if err != nil {
+			w.printf("error downloading attachments for %s: %v", path, err)
+			continue
+		}
+
+		if err := writeFileAtomic(path, []byte(text), 0644); err != nil {
+			w.printf("error writing %s: %v", path, err)
+			continue
+		}
+		if err := recordPulled(path, []byte(text)); err != nil {
+			w.printf("error recording %s: %v", path, err)
+		}
+		f.synced = text
+		f.version = doc.Version
+		f.updatedAt = doc.UpdatedAt
+		w.printf("pulled %s", path)
+	}
+}
+
+func (w *docWatcher) printf(format string, args ...any) {
+	fmt.Fprintf(w.status, "%s %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
+
+// watchedFiles expands paths into the <docID>.md files they refer to
+func watchedFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, filepath.Clean(path))
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(path, "*.md"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 500*time.Millisecond, "wait this long after the last save before pushing")
+	watchCmd.Flags().DurationVar(&watchPollInterval, "poll-interval", 30*time.Second, "how often to check Outline for remote edits")
+
+	RootCmd.AddCommand(watchCmd)
+}
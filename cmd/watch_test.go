@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/outlinetest"
+)
+
+func newTestWatcher(t *testing.T, srv *outlinetest.Server, paths ...string) *docWatcher {
+	t.Helper()
+	w := &docWatcher{
+		client: newClient(srv.Config()),
+		status: io.Discard,
+		files:  make(map[string]*watchedFile),
+	}
+	for _, path := range paths {
+		if err := w.track(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func TestWatchPushesLocalChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-id.md")
+
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original"})
+
+	if err := os.WriteFile(path, []byte("Original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWatcher(t, srv, path)
+
+	if err := os.WriteFile(path, []byte("Saved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.pushLocal(path)
+
+	if text := remoteText(t, srv, "test-id"); text != "Saved" {
+		t.Errorf("expected remote content %q, got %q", "Saved", text)
+	}
+}
+
+func TestWatchSkipsPushOnRemoteConflict(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-id.md")
+
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original"})
+
+	if err := os.WriteFile(path, []byte("Original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWatcher(t, srv, path)
+	var status strings.Builder
+	w.status = &status
+
+	updateText(t, srv, "test-id", "Remote edit")
+	if err := os.WriteFile(path, []byte("Saved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.pushLocal(path)
+
+	if text := remoteText(t, srv, "test-id"); text != "Remote edit" {
+		t.Errorf("expected the remote edit to be kept, got %q", text)
+	}
+	if !strings.Contains(status.String(), "conflict: "+path) {
+		t.Errorf("expected a conflict to be reported, got %q", status.String())
+	}
+}
+
+func TestWatchDryRunKeepsPullRecord(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-id.md")
+
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original"})
+	if err := RootCmd.PersistentFlags().Set("dry-run", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("Original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordPulled(path, []byte("Original")); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, pulledFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWatcher(t, srv, path)
+
+	if err := os.WriteFile(path, []byte("Saved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.pushLocal(path)
+
+	if text := remoteText(t, srv, "test-id"); text != "Original" {
+		t.Errorf("dry run changed the remote document to %q", text)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, pulledFile)); string(after) != string(before) {
+		t.Errorf("dry run changed the pull record:\n%s", after)
+	}
+	if w.files[path].synced != "Original" {
+		t.Errorf("dry run moved the synced baseline to %q", w.files[path].synced)
+	}
+}
+
+// statusLines forwards each status line written by a watcher
+type statusLines chan string
+
+func (c statusLines) Write(p []byte) (int, error) {
+	c <- string(p)
+	return len(p), nil
+}
+
+func TestWatchPushesPendingSavesOnStop(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test-id.md")
+
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "test-id", Text: "Original"})
+	if err := watchCmd.Flags().Set("debounce", "1h"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte("Original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWatcher(t, srv)
+	status := make(statusLines, 10)
+	w.status = status
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- w.run(ctx, []string{path}) }()
+	<-status // watching
+
+	if err := os.WriteFile(path, []byte("Saved"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Give the watcher time to see the save and start its debounce timer
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if text := remoteText(t, srv, "test-id"); text != "Saved" {
+		t.Errorf("expected the pending save to be pushed on stop, got %q", text)
+	}
+}
+
+func TestWatchPullsRemoteChangesIntoCleanFiles(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.md")
+	dirty := filepath.Join(dir, "dirty.md")
+
+	srv := useFakeServer(t)
+	srv.AddDocument(api.Document{ID: "clean", Text: "Original"})
+	srv.AddDocument(api.Document{ID: "dirty", Text: "Original"})
+
+	if err := os.WriteFile(clean, []byte("Original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dirty, []byte("Unsaved local edit"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := newTestWatcher(t, srv, clean, dirty)
+
+	// Simulate edits made by someone else
+	for _, id := range []string{"clean", "dirty"} {
+		updateText(t, srv, id, "Remote edit")
+	}
+	w.pullRemote()
+
+	if content, _ := os.ReadFile(clean); string(content) != "Remote edit" {
+		t.Errorf("expected clean file to be updated, got %q", string(content))
+	}
+	if content, _ := os.ReadFile(dirty); string(content) != "Unsaved local edit" {
+		t.Errorf("expected dirty file to be left alone, got %q", string(content))
+	}
+}
+
+func TestWatchSkipsOtherMarkdownFiles(t *testing.T) {
+	srv := useFakeServer(t)
+	doc := srv.AddDocument(api.Document{Title: "Runbook", Text: "Steps"})
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{doc.ID + ".md": "Steps", "README.md": "About", "notes.v2.md": "Draft"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := watchedFiles([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := newTestWatcher(t, srv)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := w.run(ctx, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := w.files[filepath.Join(dir, doc.ID+".md")]; !ok || len(w.files) != 1 {
+		t.Errorf("expected only the pulled document watched, got %v", w.files)
+	}
+}
@@ -2,24 +2,111 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type Config struct {
-	APIKey     string `json:"api_key"`
-	OutlineURL string `json:"outline_url"`
+	APIKey     string       `json:"api_key"`
+	OutlineURL string       `json:"outline_url"`
+	Backups    BackupConfig `json:"backups"`
+	TransportConfig
+	// Profiles are named configurations for other Outline instances,
+	// such as a staging mirror
+	Profiles map[string]*Config `json:"profiles,omitempty"`
+}
+
+// TransportConfig controls how the CLI connects to Outline. Its fields sit
+// at the top level of config.json.
+type TransportConfig struct {
+	// Proxy is the URL of an HTTP or SOCKS5 proxy. The HTTPS_PROXY and
+	// NO_PROXY environment variables are used when it is empty.
+	Proxy string `json:"proxy"`
+	// CAFile is a PEM bundle of certificate authorities trusted in addition
+	// to the system ones
+	CAFile string `json:"ca_file"`
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+	// ClientCert and ClientKey are PEM files for mutual TLS
+	ClientCert string `json:"client_cert"`
+	ClientKey  string `json:"client_key"`
+	// ConnectTimeout limits dialing and the TLS handshake
+	ConnectTimeout Duration `json:"connect_timeout"`
+	// ReadTimeout limits the wait for a response once a request is sent
+	ReadTimeout Duration `json:"read_timeout"`
+	// Headers are added to every request to Outline
+	Headers map[string]string `json:"headers"`
+	// RateLimit spaces requests to Outline at least this far apart
+	RateLimit Duration `json:"rate_limit"`
+	// CacheTTL serves repeated read-only calls from memory for this long
+	// within one command
+	CacheTTL Duration `json:"cache_ttl"`
+}
+
+// Duration is a time.Duration written in config.json as a string such as
+// "30s", or as a number of seconds
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\" or a number of seconds")
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// BackupConfig controls the snapshots of remote content taken before it is
+// overwritten
+type BackupConfig struct {
+	// Disabled turns automatic backups off
+	Disabled bool `json:"disabled"`
+	// Dir is where snapshots are stored, ~/.outline-cli/backups by default
+	Dir string `json:"dir"`
+	// Keep is the number of snapshots kept per document, 20 by default
+	Keep int `json:"keep"`
+	// MaxAgeDays removes snapshots older than this many days when set
+	MaxAgeDays int `json:"max_age_days"`
 }
 
 var LoadConfig = loadConfig
 
-func loadConfig() (*Config, error) {
+// LoadProfile returns a named profile from the config. Profiles are
+// complete configurations of their own and inherit nothing.
+var LoadProfile = loadProfile
+
+// Dir returns the directory holding the CLI's configuration and state
+func Dir() (string, error) {
 	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".outline-cli"), nil
+}
+
+func loadConfig() (*Config, error) {
+	dir, err := Dir()
 	if err != nil {
 		return nil, err
 	}
 
-	configPath := filepath.Join(home, ".outline-cli", "config.json")
+	configPath := filepath.Join(dir, "config.json")
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, err
@@ -32,3 +119,15 @@ func loadConfig() (*Config, error) {
 
 	return &config, nil
 }
+
+func loadProfile(name string) (*Config, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	profile, ok := config.Profiles[name]
+	if !ok || profile == nil {
+		return nil, fmt.Errorf("profile %q not found in config", name)
+	}
+	return profile, nil
+}
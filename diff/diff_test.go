@@ -0,0 +1,53 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedIdentical(t *testing.T) {
+	if got := Unified("a", "b", "same\n", "same\n"); got != "" {
+		t.Errorf("expected empty diff, got %q", got)
+	}
+}
+
+func TestUnifiedSingleChange(t *testing.T) {
+	a := "one\ntwo\nthree\n"
+	b := "one\n2\nthree\n"
+	want := `--- remote
++++ local
+@@ -1,3 +1,3 @@
+ one
+-two
++2
+ three
+`
+	if got := Unified("remote", "local", a, b); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	var lines []string
+	for i := 0; i < 20; i++ {
+		lines = append(lines, "line\n")
+	}
+	a := "first\n" + strings.Join(lines, "") + "last\n"
+	b := "FIRST\n" + strings.Join(lines, "") + "LAST\n"
+
+	got := Unified("a", "b", a, b)
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Errorf("expected 2 hunks, got %d:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,4 +1,4 @@") || !strings.Contains(got, "@@ -19,4 +19,4 @@") {
+		t.Errorf("unexpected hunk headers:\n%s", got)
+	}
+}
+
+func TestUnifiedMissingNewline(t *testing.T) {
+	got := Unified("a", "b", "", "added")
+	want := "--- a\n+++ b\n@@ -0,0 +1 @@\n+added\n\\ No newline at end of file\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -2,9 +2,19 @@ module outline-cli
 
 go 1.23.0
 
-require github.com/spf13/cobra v1.8.1
+require (
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/itchyny/gojq v0.12.16
+	github.com/klauspost/compress v1.17.11
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/term v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/itchyny/timefmt-go v0.1.6 // indirect
+	golang.org/x/sys v0.20.0 // indirect
 )
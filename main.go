@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -9,7 +10,11 @@ import (
 
 func main() {
 	if err := cmd.RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
+		// Exit status 2 tells scripts a dry run found changes to make
+		if errors.Is(err, cmd.ErrChangesPending) {
+			os.Exit(2)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,382 @@
+package outlinetest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"outline-cli/api"
+)
+
+// fileOperation is an export or import. The work is done when it starts;
+// polls makes fileOperations.info report it as running for a while first,
+// as Outline does while its worker is busy.
+type fileOperation struct {
+	api.FileOperation
+	// archiveID is the attachment holding an export's archive
+	archiveID string
+	// final is the state reported once polls run out
+	final string
+	polls int
+}
+
+// jsonArchive is a collection in a JSON export
+type jsonArchive struct {
+	Collection api.Collection          `json:"collection"`
+	Documents  map[string]api.Document `json:"documents"`
+}
+
+func (s *Server) collectionsExport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     string `json:"id"`
+		Format string `json:"format"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	coll := s.findCollection(req.ID)
+	if coll == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Collection not found")
+		return
+	}
+	s.startExport(w, []*api.Collection{coll}, req.Format, coll.ID)
+}
+
+func (s *Server) collectionsExportAll(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Format string `json:"format"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.startExport(w, s.collections, req.Format, "")
+}
+
+func (s *Server) startExport(w http.ResponseWriter, collections []*api.Collection, format string, collectionID string) {
+	switch format {
+	case "":
+		format = api.FormatMarkdown
+	case api.FormatMarkdown, api.FormatJSON, api.FormatHTML:
+	default:
+		writeError(w, http.StatusBadRequest, "validation_error", "format: Invalid enum value")
+		return
+	}
+
+	data, err := s.exportArchive(collections, format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "internal_error", err.Error())
+		return
+	}
+	name := "export.zip"
+	if collectionID != "" {
+		name = archiveName(collections[0].Name) + "-export.zip"
+	}
+	archive := s.newAttachment(name, "application/zip", int64(len(data)), "")
+	archive.Data = data
+	archive.uploaded = true
+
+	op := s.newFileOperation("export", format, api.FileOperationComplete)
+	op.Name = name
+	op.Size = archive.Size
+	op.CollectionID = collectionID
+	op.archiveID = archive.ID
+	writeData(w, map[string]any{"fileOperation": op.FileOperation})
+}
+
+func (s *Server) collectionsImport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AttachmentID string `json:"attachmentId"`
+		Format       string `json:"format"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Format == "" {
+		req.Format = api.FormatMarkdown
+	}
+	if req.Format != api.FormatMarkdown && req.Format != api.FormatJSON {
+		writeError(w, http.StatusBadRequest, "validation_error", "format: Invalid enum value")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attachments[req.AttachmentID]
+	if !ok || !a.uploaded {
+		writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+		return
+	}
+
+	op := s.newFileOperation("import", req.Format, api.FileOperationComplete)
+	op.Name = a.Name
+	op.Size = a.Size
+	if err := s.importArchive(a.Data, req.Format); err != nil {
+		op.final = api.FileOperationError
+		op.Error = err.Error()
+	}
+	writeData(w, map[string]any{"fileOperation": op.FileOperation})
+}
+
+func (s *Server) fileOperationsInfo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.fileOperations[req.ID]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "File operation not found")
+		return
+	}
+	if op.polls > 0 {
+		op.polls--
+	} else {
+		op.State = op.final
+	}
+	writeData(w, op.FileOperation)
+}
+
+// fileOperationsRedirect redirects to the archive of a completed export
+func (s *Server) fileOperationsRedirect(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" && r.Method == http.MethodPost {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if !decode(w, r, &req) {
+			return
+		}
+		id = req.ID
+	}
+
+	s.mu.Lock()
+	op, ok := s.fileOperations[id]
+	s.mu.Unlock()
+
+	switch {
+	case !ok || op.Type != "export":
+		writeError(w, http.StatusNotFound, "not_found", "File operation not found")
+	case op.State != api.FileOperationComplete:
+		writeError(w, http.StatusBadRequest, "validation_error", "File operation is not complete yet")
+	default:
+		http.Redirect(w, r, s.URL+filesPath+op.archiveID, http.StatusFound)
+	}
+}
+
+func (s *Server) newFileOperation(opType string, format string, final string) *fileOperation {
+	op := &fileOperation{
+		FileOperation: api.FileOperation{
+			ID:     s.newID(),
+			Type:   opType,
+			State:  api.FileOperationCreating,
+			Format: format,
+		},
+		final: final,
+		polls: 1,
+	}
+	s.fileOperations[op.ID] = op
+	return op
+}
+
+// exportArchive zips collections the way Outline lays out exports: a
+// folder per collection with a file per document and a folder of its
+// children next to it, or a JSON file per collection
+func (s *Server) exportArchive(collections []*api.Collection, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, c := range collections {
+		if format == api.FormatJSON {
+			archive := jsonArchive{Collection: *c, Documents: make(map[string]api.Document)}
+			for _, id := range s.order {
+				if doc := s.documents[id]; doc.CollectionID == c.ID {
+					archive.Documents[id] = *doc
+				}
+			}
+			data, err := json.MarshalIndent(archive, "", "  ")
+			if err != nil {
+				return nil, err
+			}
+			if err := writeZipFile(zw, archiveName(c.Name)+".json", data); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err := s.exportTree(zw, archiveName(c.Name), c.ID, "", format); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *Server) exportTree(zw *zip.Writer, dir string, collectionID string, parent string, format string) error {
+	for _, id := range s.order {
+		doc := s.documents[id]
+		if doc.CollectionID != collectionID || doc.ParentDocumentID != parent {
+			continue
+		}
+		name := dir + "/" + archiveName(doc.Title)
+		// The fake doesn't render Markdown; HTML exports hold the source
+		content := "# " + doc.Title + "\n\n" + doc.Text
+		ext := ".md"
+		if format == api.FormatHTML {
+			content = "<h1>" + html.EscapeString(doc.Title) + "</h1>\n<pre>" + html.EscapeString(doc.Text) + "</pre>\n"
+			ext = ".html"
+		}
+		if err := writeZipFile(zw, name+ext, []byte(content)); err != nil {
+			return err
+		}
+		if err := s.exportTree(zw, name, collectionID, doc.ID, format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importArchive creates a collection for every collection in an archive
+// laid out like exportArchive's
+func (s *Server) importArchive(data []byte, format string) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("invalid zip file: %w", err)
+	}
+	files := append([]*zip.File(nil), zr.File...)
+	// Parents sort before the folders holding their children
+	sort.SliceStable(files, func(i, j int) bool {
+		return strings.Count(files[i].Name, "/") < strings.Count(files[j].Name, "/")
+	})
+
+	collections := make(map[string]*api.Collection)
+	docs := make(map[string]*api.Document)
+	imported := 0
+	for _, f := range files {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return err
+		}
+
+		if format == api.FormatJSON {
+			if path.Ext(f.Name) != ".json" {
+				continue
+			}
+			var archive jsonArchive
+			if err := json.Unmarshal(content, &archive); err != nil {
+				return fmt.Errorf("%s: %w", f.Name, err)
+			}
+			s.importJSON(archive)
+			imported++
+			continue
+		}
+
+		dir, file := path.Split(f.Name)
+		dir = strings.TrimSuffix(dir, "/")
+		if path.Ext(file) != ".md" || dir == "" {
+			continue
+		}
+		collName, _, _ := strings.Cut(dir, "/")
+		coll, ok := collections[collName]
+		if !ok {
+			coll = s.addCollection(collName)
+			collections[collName] = coll
+		}
+		title := strings.TrimSuffix(file, ".md")
+		doc := api.Document{Title: title, Text: stripTitle(string(content), title), CollectionID: coll.ID}
+		if parent, ok := docs[dir]; ok {
+			doc.ParentDocumentID = parent.ID
+		}
+		docs[strings.TrimSuffix(f.Name, ".md")] = s.createDocument(doc)
+		imported++
+	}
+	if imported == 0 {
+		return fmt.Errorf("no documents found in archive")
+	}
+	return nil
+}
+
+func (s *Server) importJSON(archive jsonArchive) {
+	coll := s.addCollection(archive.Collection.Name)
+	created := make(map[string]string)
+	var create func(id string) string
+	create = func(id string) string {
+		if newID, ok := created[id]; ok {
+			return newID
+		}
+		doc, ok := archive.Documents[id]
+		if !ok {
+			return ""
+		}
+		parent := ""
+		if doc.ParentDocumentID != "" {
+			parent = create(doc.ParentDocumentID)
+		}
+		stored := s.createDocument(api.Document{Title: doc.Title, Text: doc.Text, CollectionID: coll.ID, ParentDocumentID: parent})
+		created[id] = stored.ID
+		return stored.ID
+	}
+	ids := make([]string, 0, len(archive.Documents))
+	for id := range archive.Documents {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		create(id)
+	}
+}
+
+// stripTitle removes the heading Outline puts above the text in Markdown
+// exports
+func stripTitle(text string, title string) string {
+	if rest, ok := strings.CutPrefix(text, "# "+title+"\n"); ok {
+		return strings.TrimPrefix(rest, "\n")
+	}
+	return text
+}
+
+// archiveName makes a title usable as a file name in an archive
+func archiveName(title string) string {
+	return strings.NewReplacer("/", "-", "\\", "-").Replace(title)
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,899 @@
+package outlinetest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"outline-cli/api"
+)
+
+// filesPath is where uploaded files are served from, standing in for the
+// presigned storage URLs attachments.redirect points at
+const filesPath = "/files/"
+
+// slugSuffix extracts the url-id from a "title-slug-urlId" reference
+var slugSuffix = regexp.MustCompile(`(?:^|-)([0-9A-Za-z]{10,15})$`)
+
+func (s *Server) handlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"auth.info":               s.authInfo,
+		"documents.info":          s.documentsInfo,
+		"documents.list":          s.documentsList,
+		"documents.create":        s.documentsCreate,
+		"documents.update":        s.documentsUpdate,
+		"documents.delete":        s.documentsDelete,
+		"documents.move":          s.documentsMove,
+		"documents.search":        s.documentsSearch,
+		"documents.search_titles": s.documentsSearchTitles,
+		"documents.drafts":        s.documentsWithStatus(StatusDraft),
+		"documents.archived":      s.documentsWithStatus(StatusArchived),
+		"documents.archive":       s.documentsArchive,
+		"collections.list":        s.collectionsList,
+		"collections.info":        s.collectionsInfo,
+		"collections.documents":   s.collectionsDocuments,
+		"collections.create":      s.collectionsCreate,
+		"collections.update":      s.collectionsUpdate,
+		"revisions.list":          s.revisionsList,
+		"revisions.info":          s.revisionsInfo,
+		"events.list":             s.eventsList,
+		"attachments.create":      s.attachmentsCreate,
+		"attachments.redirect":    s.attachmentsRedirect,
+		"attachments.delete":      s.attachmentsDelete,
+		"files.create":            s.filesCreate,
+		"collections.export":      s.collectionsExport,
+		"collections.export_all":  s.collectionsExportAll,
+		"collections.import":      s.collectionsImport,
+		"fileOperations.info":     s.fileOperationsInfo,
+		"fileOperations.redirect": s.fileOperationsRedirect,
+	}
+}
+
+func (s *Server) authInfo(w http.ResponseWriter, r *http.Request) {
+	writeData(w, map[string]any{
+		"user": map[string]string{"id": "00000000-0000-4000-8000-000000000000", "name": "Test User", "email": "test@example.com"},
+		"team": map[string]string{"id": "00000000-0000-4000-8000-000000000000", "name": "Test Team", "url": s.URL},
+	})
+}
+
+func (s *Server) documentsInfo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID      string `json:"id"`
+		ShareID string `json:"shareId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.ShareID != "" {
+		shared, ok := s.shares[req.ShareID]
+		if !ok {
+			writeError(w, http.StatusNotFound, "not_found", "Share not found")
+			return
+		}
+		doc := s.documents[shared]
+		if req.ID != "" {
+			doc = s.findDocument(req.ID)
+			if doc == nil || !s.within(doc.ID, shared) {
+				doc = nil
+			}
+		}
+		if doc == nil {
+			writeError(w, http.StatusNotFound, "not_found", "Document not found")
+			return
+		}
+		writeData(w, doc)
+		return
+	}
+
+	doc := s.findDocument(req.ID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+	writeData(w, doc)
+}
+
+func (s *Server) documentsList(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		page
+		CollectionID     string `json:"collectionId"`
+		ParentDocumentID string `json:"parentDocumentId"`
+		Sort             string `json:"sort"`
+		Direction        string `json:"direction"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := []api.Document{}
+	for _, id := range s.order {
+		doc := s.documents[id]
+		if req.CollectionID != "" && doc.CollectionID != req.CollectionID {
+			continue
+		}
+		if req.ParentDocumentID != "" && doc.ParentDocumentID != req.ParentDocumentID {
+			continue
+		}
+		if _, unpublished := s.status[id]; unpublished {
+			continue
+		}
+		docs = append(docs, *doc)
+	}
+
+	switch req.Sort {
+	case "title":
+		sort.SliceStable(docs, func(i, j int) bool { return docs[i].Title < docs[j].Title })
+	case "createdAt", "index":
+		// Already in creation order
+	default:
+		sort.SliceStable(docs, func(i, j int) bool { return docs[i].UpdatedAt.Before(docs[j].UpdatedAt) })
+		if req.Direction == "" {
+			req.Direction = "DESC"
+		}
+	}
+	if strings.EqualFold(req.Direction, "DESC") {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+	}
+
+	data, pg := paginate("documents.list", docs, req.page)
+	writePage(w, data, pg)
+}
+
+func (s *Server) documentsCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title            string `json:"title"`
+		Text             string `json:"text"`
+		CollectionID     string `json:"collectionId"`
+		ParentDocumentID string `json:"parentDocumentId"`
+		Publish          bool   `json:"publish"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findCollection(req.CollectionID) == nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "collectionId: Invalid collection")
+		return
+	}
+	if req.ParentDocumentID != "" {
+		parent, ok := s.documents[req.ParentDocumentID]
+		if !ok || parent.CollectionID != req.CollectionID {
+			writeError(w, http.StatusBadRequest, "validation_error", "parentDocumentId: Invalid parent document")
+			return
+		}
+	}
+
+	doc := s.createDocument(api.Document{
+		Title:            req.Title,
+		Text:             req.Text,
+		CollectionID:     req.CollectionID,
+		ParentDocumentID: req.ParentDocumentID,
+	})
+	if !req.Publish {
+		s.status[doc.ID] = StatusDraft
+	}
+	writeData(w, doc)
+}
+
+// documentsWithStatus lists the documents in a state other than published
+func (s *Server) documentsWithStatus(status string) http.HandlerFunc {
+	method := "documents." + map[string]string{StatusDraft: "drafts", StatusArchived: "archived"}[status]
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req page
+		if !decode(w, r, &req) {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		docs := []api.Document{}
+		for _, id := range s.order {
+			if s.status[id] == status {
+				docs = append(docs, *s.documents[id])
+			}
+		}
+		data, pg := paginate(method, docs, req)
+		writePage(w, data, pg)
+	}
+}
+
+func (s *Server) documentsArchive(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.findDocument(req.ID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+	s.status[doc.ID] = StatusArchived
+	s.recordEvent("documents.archive", doc)
+	writeData(w, doc)
+}
+
+func (s *Server) documentsUpdate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID    string  `json:"id"`
+		Title *string `json:"title"`
+		Text  *string `json:"text"`
+		Icon  *string `json:"icon"`
+		Emoji *string `json:"emoji"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.findDocument(req.ID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+
+	changed := false
+	if req.Title != nil && *req.Title != doc.Title {
+		doc.Title = *req.Title
+		changed = true
+	}
+	if req.Text != nil && *req.Text != doc.Text {
+		doc.Text = *req.Text
+		changed = true
+	}
+	if req.Icon != nil && *req.Icon != doc.Icon {
+		doc.Icon = *req.Icon
+		changed = true
+	}
+	if req.Emoji != nil && *req.Emoji != doc.Emoji {
+		doc.Emoji = *req.Emoji
+		changed = true
+	}
+	if changed {
+		s.saveRevision(doc)
+		s.recordEvent("documents.update", doc)
+	}
+	writeData(w, doc)
+}
+
+func (s *Server) documentsDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.findDocument(req.ID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+
+	// Deleting a document deletes everything nested below it
+	for _, id := range append([]string(nil), s.order...) {
+		if s.within(id, doc.ID) {
+			s.recordEvent("documents.delete", s.documents[id])
+			s.removeDocument(id)
+		}
+	}
+	writeData(w, map[string]bool{"success": true})
+}
+
+func (s *Server) documentsMove(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID               string `json:"id"`
+		CollectionID     string `json:"collectionId"`
+		ParentDocumentID string `json:"parentDocumentId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.findDocument(req.ID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+	if req.CollectionID == "" {
+		req.CollectionID = doc.CollectionID
+	}
+	if s.findCollection(req.CollectionID) == nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "collectionId: Invalid collection")
+		return
+	}
+	if req.ParentDocumentID != "" {
+		parent, ok := s.documents[req.ParentDocumentID]
+		if !ok || parent.CollectionID != req.CollectionID || s.within(parent.ID, doc.ID) {
+			writeError(w, http.StatusBadRequest, "validation_error", "parentDocumentId: Invalid parent document")
+			return
+		}
+	}
+
+	for _, id := range s.order {
+		if s.within(id, doc.ID) {
+			s.documents[id].CollectionID = req.CollectionID
+		}
+	}
+	doc.ParentDocumentID = req.ParentDocumentID
+	doc.UpdatedAt = s.now()
+	s.recordEvent("documents.move", doc)
+	writeData(w, map[string]any{"documents": []*api.Document{doc}})
+}
+
+func (s *Server) documentsSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		page
+		Query        string `json:"query"`
+		CollectionID string `json:"collectionId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(req.Query)
+	results := []api.SearchResult{}
+	for _, id := range s.order {
+		doc := s.documents[id]
+		if req.CollectionID != "" && doc.CollectionID != req.CollectionID {
+			continue
+		}
+		inTitle := strings.Contains(strings.ToLower(doc.Title), query)
+		if !inTitle && !strings.Contains(strings.ToLower(doc.Text), query) {
+			continue
+		}
+		ranking := 0.5
+		if inTitle {
+			ranking = 1
+		}
+		results = append(results, api.SearchResult{Ranking: ranking, Context: snippet(doc.Text, query), Document: *doc})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Ranking > results[j].Ranking })
+
+	data, pg := paginate("documents.search", results, req.page)
+	writePage(w, data, pg)
+}
+
+func (s *Server) documentsSearchTitles(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		page
+		Query string `json:"query"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(req.Query)
+	docs := []*api.Document{}
+	for _, id := range s.order {
+		if doc := s.documents[id]; strings.Contains(strings.ToLower(doc.Title), query) {
+			docs = append(docs, doc)
+		}
+	}
+
+	data, pg := paginate("documents.search_titles", docs, req.page)
+	writePage(w, data, pg)
+}
+
+func (s *Server) collectionsList(w http.ResponseWriter, r *http.Request) {
+	var req page
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collections := make([]api.Collection, len(s.collections))
+	for i, c := range s.collections {
+		collections[i] = *c
+	}
+	data, pg := paginate("collections.list", collections, req)
+	writePage(w, data, pg)
+}
+
+func (s *Server) collectionsInfo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.findCollection(req.ID)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Collection not found")
+		return
+	}
+	writeData(w, c)
+}
+
+func (s *Server) collectionsCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Color       string `json:"color"`
+		Icon        string `json:"icon"`
+		Permission  string `json:"permission"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "validation_error", "name: Required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.addCollection(req.Name)
+	c.Description, c.Color, c.Icon, c.Permission = req.Description, req.Color, req.Icon, req.Permission
+	writeData(w, c)
+}
+
+func (s *Server) collectionsUpdate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID          string  `json:"id"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		Color       *string `json:"color"`
+		Icon        *string `json:"icon"`
+		Permission  *string `json:"permission"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.findCollection(req.ID)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Collection not found")
+		return
+	}
+	if req.Name != nil {
+		c.Name = *req.Name
+	}
+	if req.Description != nil {
+		c.Description = *req.Description
+	}
+	if req.Color != nil {
+		c.Color = *req.Color
+	}
+	if req.Icon != nil {
+		c.Icon = *req.Icon
+	}
+	if req.Permission != nil {
+		c.Permission = *req.Permission
+	}
+	writeData(w, c)
+}
+
+func (s *Server) collectionsDocuments(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.findCollection(req.ID)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Collection not found")
+		return
+	}
+	writeData(w, s.navigation(c.ID, ""))
+}
+
+func (s *Server) revisionsList(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		page
+		DocumentID string `json:"documentId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc := s.findDocument(req.DocumentID)
+	if doc == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+
+	// Newest first, as Outline lists them
+	revisions := s.revisions[doc.ID]
+	newest := make([]Revision, len(revisions))
+	for i, rev := range revisions {
+		newest[len(revisions)-1-i] = rev
+	}
+	data, pg := paginate("revisions.list", newest, req.page)
+	writePage(w, data, pg)
+}
+
+func (s *Server) revisionsInfo(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, revisions := range s.revisions {
+		for _, rev := range revisions {
+			if rev.ID == req.ID {
+				writeData(w, rev)
+				return
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found", "Revision not found")
+}
+
+func (s *Server) eventsList(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		page
+		CollectionID string `json:"collectionId"`
+		DocumentID   string `json:"documentId"`
+		Direction    string `json:"direction"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []api.Event{}
+	for _, e := range s.events {
+		if req.CollectionID != "" && e.CollectionID != req.CollectionID {
+			continue
+		}
+		if req.DocumentID != "" && e.DocumentID != req.DocumentID {
+			continue
+		}
+		events = append(events, e)
+	}
+	if !strings.EqualFold(req.Direction, "ASC") {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+	}
+
+	data, pg := paginate("events.list", events, req.page)
+	writePage(w, data, pg)
+}
+
+func (s *Server) attachmentsCreate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size"`
+		DocumentID  string `json:"documentId"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+	if req.Name == "" {
+		writeError(w, http.StatusBadRequest, "validation_error", "name: Required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.DocumentID != "" && s.findDocument(req.DocumentID) == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Document not found")
+		return
+	}
+
+	a := s.newAttachment(req.Name, req.ContentType, req.Size, req.DocumentID)
+	writeData(w, map[string]any{
+		"uploadUrl":  "/api/files.create",
+		"form":       map[string]string{"key": a.ID},
+		"attachment": a.Attachment,
+	})
+}
+
+// filesCreate receives the multipart upload that follows attachments.create
+func (s *Server) filesCreate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "Invalid upload: "+err.Error())
+		return
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "file: Required")
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "Invalid upload: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attachments[r.FormValue("key")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+		return
+	}
+	a.Data = data
+	a.Size = int64(len(data))
+	a.uploaded = true
+	writeData(w, map[string]bool{"success": true})
+}
+
+// attachmentsRedirect redirects to the file's storage URL, as Outline does
+func (s *Server) attachmentsRedirect(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" && r.Method == http.MethodPost {
+		var req struct {
+			ID string `json:"id"`
+		}
+		if !decode(w, r, &req) {
+			return
+		}
+		id = req.ID
+	}
+
+	s.mu.Lock()
+	a, ok := s.attachments[id]
+	ok = ok && a.uploaded
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+		return
+	}
+	http.Redirect(w, r, s.URL+filesPath+id, http.StatusFound)
+}
+
+func (s *Server) attachmentsDelete(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID string `json:"id"`
+	}
+	if !decode(w, r, &req) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.attachments[req.ID]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+		return
+	}
+	delete(s.attachments, req.ID)
+	writeData(w, map[string]bool{"success": true})
+}
+
+// serveFile serves an uploaded file from its storage URL
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, filesPath)
+
+	s.mu.Lock()
+	a, ok := s.attachments[id]
+	var data []byte
+	var name, contentType string
+	if ok {
+		data, name, contentType = a.Data, a.Name, a.ContentType
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	io.Copy(w, bytes.NewReader(data))
+}
+
+// createDocument stores a new document with a first revision
+func (s *Server) createDocument(doc api.Document) *api.Document {
+	if doc.ID == "" {
+		doc.ID = s.newID()
+	}
+	if doc.URLID == "" {
+		doc.URLID = urlID(doc.ID)
+	}
+	stored := &doc
+	s.documents[doc.ID] = stored
+	s.order = append(s.order, doc.ID)
+	s.saveRevision(stored)
+	s.recordEvent("documents.create", stored)
+	return stored
+}
+
+// saveRevision bumps the document's version and records the new content
+func (s *Server) saveRevision(doc *api.Document) {
+	doc.Version++
+	doc.UpdatedAt = s.now()
+	s.revisions[doc.ID] = append(s.revisions[doc.ID], Revision{
+		ID:         s.newID(),
+		DocumentID: doc.ID,
+		Title:      doc.Title,
+		Text:       doc.Text,
+		Version:    doc.Version,
+		CreatedAt:  doc.UpdatedAt,
+	})
+}
+
+func (s *Server) recordEvent(name string, doc *api.Document) {
+	s.events = append(s.events, api.Event{
+		ID:           s.newID(),
+		Name:         name,
+		DocumentID:   doc.ID,
+		CollectionID: doc.CollectionID,
+		CreatedAt:    s.now(),
+	})
+}
+
+func (s *Server) removeDocument(id string) {
+	delete(s.documents, id)
+	delete(s.status, id)
+	for i, o := range s.order {
+		if o == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Server) newAttachment(name string, contentType string, size int64, documentID string) *Attachment {
+	id := s.newID()
+	a := &Attachment{
+		Attachment: api.Attachment{
+			ID:          id,
+			Name:        name,
+			ContentType: contentType,
+			Size:        size,
+			URL:         api.AttachmentURL(id),
+		},
+		DocumentID: documentID,
+	}
+	s.attachments[id] = a
+	return a
+}
+
+// findDocument looks a document up by ID, url-id or "title-slug-urlId"
+func (s *Server) findDocument(ref string) *api.Document {
+	if doc, ok := s.documents[ref]; ok {
+		return doc
+	}
+	if m := slugSuffix.FindStringSubmatch(ref); m != nil {
+		for _, doc := range s.documents {
+			if doc.URLID == m[1] {
+				return doc
+			}
+		}
+	}
+	return nil
+}
+
+// findCollection looks a collection up by ID or url-id
+func (s *Server) findCollection(ref string) *api.Collection {
+	for _, c := range s.collections {
+		if ref != "" && (c.ID == ref || c.URLID == ref) {
+			return c
+		}
+	}
+	return nil
+}
+
+// within reports whether document id is ancestor itself or nested below it
+func (s *Server) within(id string, ancestor string) bool {
+	for id != "" {
+		if id == ancestor {
+			return true
+		}
+		doc, ok := s.documents[id]
+		if !ok {
+			return false
+		}
+		id = doc.ParentDocumentID
+	}
+	return false
+}
+
+// navigation builds the document tree of a collection below parent
+func (s *Server) navigation(collectionID string, parent string) []api.NavigationNode {
+	nodes := []api.NavigationNode{}
+	for _, id := range s.order {
+		doc := s.documents[id]
+		if doc.CollectionID != collectionID || doc.ParentDocumentID != parent {
+			continue
+		}
+		if _, unpublished := s.status[id]; unpublished {
+			continue
+		}
+		nodes = append(nodes, api.NavigationNode{
+			ID:       doc.ID,
+			Title:    doc.Title,
+			URL:      fmt.Sprintf("/doc/%s-%s", slug(doc.Title), doc.URLID),
+			Children: s.navigation(collectionID, doc.ID),
+		})
+	}
+	return nodes
+}
+
+func slug(title string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, title), "-")
+}
+
+// snippet returns the text around the first match of query
+func snippet(text string, query string) string {
+	i := strings.Index(strings.ToLower(text), query)
+	if i < 0 || i > len(text) {
+		return ""
+	}
+	start := max(i-40, 0)
+	end := min(i+len(query)+40, len(text))
+	return text[start:end]
+}
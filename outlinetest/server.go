@@ -0,0 +1,430 @@
+// Package outlinetest runs an in-process fake of the Outline API for
+// hermetic tests. It serves the RPC endpoints the CLI uses from an
+// in-memory store, checks the API key, paginates lists, versions documents
+// and can be told to fail.
+//
+//	srv := outlinetest.NewServer()
+//	defer srv.Close()
+//	doc := srv.AddDocument(api.Document{Title: "Runbook", Text: "..."})
+//	client := api.DefaultClientFactory(srv.Config())
+package outlinetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"outline-cli/api"
+	"outline-cli/config"
+)
+
+// APIKey is the key the server accepts unless Server.APIKey is changed
+const APIKey = "outlinetest-api-key"
+
+// Default and maximum page sizes, matching Outline
+const (
+	DefaultLimit = 25
+	MaxLimit     = 100
+)
+
+// Fault makes calls to an endpoint fail
+type Fault struct {
+	// Status is the HTTP status to respond with, 500 if zero
+	Status int
+	// Error and Message fill the Outline error body
+	Error   string
+	Message string
+	// Delay is waited before responding, whether or not Status is set
+	Delay time.Duration
+	// Times limits how many calls fail; zero means every call
+	Times int
+}
+
+// Revision is a saved version of a document
+type Revision struct {
+	ID         string    `json:"id"`
+	DocumentID string    `json:"documentId"`
+	Title      string    `json:"title"`
+	Text       string    `json:"text"`
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Attachment is an uploaded file held by the server
+type Attachment struct {
+	api.Attachment
+	DocumentID string
+	Data       []byte
+	uploaded   bool
+}
+
+// Server is a fake Outline instance. Its methods are safe to call while
+// requests are being served.
+type Server struct {
+	*httptest.Server
+
+	// APIKey is the bearer token requests must carry. Change it before
+	// making requests.
+	APIKey string
+
+	mu             sync.Mutex
+	seq            int
+	lastTime       time.Time
+	documents      map[string]*api.Document
+	order          []string
+	revisions      map[string][]Revision
+	collections    []*api.Collection
+	attachments    map[string]*Attachment
+	shares         map[string]string
+	fileOperations map[string]*fileOperation
+	// status holds the state of documents that aren't published
+	status map[string]string
+	events []api.Event
+	faults map[string]*Fault
+	calls  []string
+}
+
+// NewServer starts a fake Outline server. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		APIKey:         APIKey,
+		documents:      make(map[string]*api.Document),
+		revisions:      make(map[string][]Revision),
+		attachments:    make(map[string]*Attachment),
+		shares:         make(map[string]string),
+		fileOperations: make(map[string]*fileOperation),
+		status:         make(map[string]string),
+		faults:         make(map[string]*Fault),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Config returns a CLI config pointing at the server with a valid key
+func (s *Server) Config() *config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &config.Config{APIKey: s.APIKey, OutlineURL: s.URL}
+}
+
+// Fail injects a fault into every call of an RPC method, such as
+// "documents.update", or of "files.create" for uploads
+func (s *Server) Fail(method string, f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f.Status == 0 && f.Delay == 0 {
+		f.Status = http.StatusInternalServerError
+	}
+	s.faults[method] = &f
+}
+
+// Calls returns the RPC methods called so far, in order
+func (s *Server) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+// AddCollection creates a collection
+func (s *Server) AddCollection(name string) api.Collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addCollection(name)
+}
+
+// AddCollectionWithID creates a collection under a fixed ID, for code that
+// refers to a known collection
+func (s *Server) AddCollectionWithID(id string, name string) api.Collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.storeCollection(id, name)
+}
+
+func (s *Server) addCollection(name string) *api.Collection {
+	return s.storeCollection(s.newID(), name)
+}
+
+func (s *Server) storeCollection(id string, name string) *api.Collection {
+	c := &api.Collection{ID: id, URLID: urlID(id), Name: name}
+	s.collections = append(s.collections, c)
+	return c
+}
+
+// Collections returns every collection in creation order
+func (s *Server) Collections() []api.Collection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	collections := make([]api.Collection, len(s.collections))
+	for i, c := range s.collections {
+		collections[i] = *c
+	}
+	return collections
+}
+
+// AddDocument stores doc as a published document. A missing ID, url-id or
+// collection is filled in; the stored document is returned.
+func (s *Server) AddDocument(doc api.Document) api.Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.CollectionID == "" {
+		if len(s.collections) == 0 {
+			s.addCollection("Default")
+		}
+		doc.CollectionID = s.collections[0].ID
+	}
+	return *s.createDocument(doc)
+}
+
+// Document states other than published
+const (
+	StatusDraft    = "draft"
+	StatusArchived = "archived"
+)
+
+// AddDraft stores doc as an unpublished draft, like AddDocument
+func (s *Server) AddDraft(doc api.Document) api.Document {
+	stored := s.AddDocument(doc)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[stored.ID] = StatusDraft
+	return stored
+}
+
+// Archive archives a stored document
+func (s *Server) Archive(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[id] = StatusArchived
+}
+
+// Status returns StatusDraft or StatusArchived for documents in those
+// states, and "published" otherwise
+func (s *Server) Status(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status, ok := s.status[id]; ok {
+		return status
+	}
+	return "published"
+}
+
+// Document returns a stored document by ID
+func (s *Server) Document(id string) (api.Document, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.documents[id]
+	if !ok {
+		return api.Document{}, false
+	}
+	return *doc, true
+}
+
+// Documents returns every stored document in creation order
+func (s *Server) Documents() []api.Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	docs := make([]api.Document, 0, len(s.order))
+	for _, id := range s.order {
+		docs = append(docs, *s.documents[id])
+	}
+	return docs
+}
+
+// Revisions returns a document's revisions, oldest first
+func (s *Server) Revisions(docID string) []Revision {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Revision(nil), s.revisions[docID]...)
+}
+
+// AddAttachment stores an uploaded file and returns its metadata
+func (s *Server) AddAttachment(name string, contentType string, data []byte, documentID string) api.Attachment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a := s.newAttachment(name, contentType, int64(len(data)), documentID)
+	a.Data = data
+	a.uploaded = true
+	return a.Attachment
+}
+
+// Attachment returns a stored attachment by ID
+func (s *Server) Attachment(id string) (Attachment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.attachments[id]
+	if !ok {
+		return Attachment{}, false
+	}
+	return *a, true
+}
+
+// Share creates a share link for a document and returns its ID
+func (s *Server) Share(docID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.newID()
+	s.shares[id] = docID
+	return id
+}
+
+// newID returns a deterministic UUID so test output is stable
+func (s *Server) newID() string {
+	s.seq++
+	return fmt.Sprintf("00000000-0000-4000-8000-%012d", s.seq)
+}
+
+// now returns the current time, strictly after any time handed out before
+// so updates always order
+func (s *Server) now() time.Time {
+	t := time.Now().UTC().Truncate(time.Millisecond)
+	if !t.After(s.lastTime) {
+		t = s.lastTime.Add(time.Millisecond)
+	}
+	s.lastTime = t
+	return t
+}
+
+// urlID derives the 10 character url-id Outline shows in document URLs
+func urlID(id string) string {
+	digits := strings.ReplaceAll(id, "-", "")
+	if len(digits) < 9 {
+		// Tests may store documents under short IDs such as "doc-1"
+		digits = strings.Repeat("0", 9-len(digits)) + digits
+	}
+	return "t" + digits[len(digits)-9:]
+}
+
+// serveHTTP checks auth and faults, then dispatches to the endpoint
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, filesPath) {
+		// Storage URLs are presigned and carry no credentials
+		s.serveFile(w, r)
+		return
+	}
+
+	method, ok := strings.CutPrefix(r.URL.Path, "/api/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Resource not found")
+		return
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, method)
+	fault := s.takeFault(method)
+	key := s.APIKey
+	s.mu.Unlock()
+
+	if fault != nil {
+		time.Sleep(fault.Delay)
+		if fault.Status != 0 {
+			writeError(w, fault.Status, fault.Error, fault.Message)
+			return
+		}
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+key {
+		writeError(w, http.StatusUnauthorized, "authentication_required", "Authentication required")
+		return
+	}
+
+	handler, ok := s.handlers()[method]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "Resource not found")
+		return
+	}
+	handler(w, r)
+}
+
+// takeFault returns the fault for method, using up one of its Times
+func (s *Server) takeFault(method string) *Fault {
+	f, ok := s.faults[method]
+	if !ok {
+		return nil
+	}
+	if f.Times > 0 {
+		f.Times--
+		if f.Times == 0 {
+			delete(s.faults, method)
+		}
+	}
+	copied := *f
+	return &copied
+}
+
+// pagination is the paging block of Outline list responses
+type pagination struct {
+	Offset   int    `json:"offset"`
+	Limit    int    `json:"limit"`
+	NextPath string `json:"nextPath,omitempty"`
+}
+
+type page struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// paginate slices items for the requested page
+func paginate[T any](method string, items []T, p page) ([]T, pagination) {
+	limit := p.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+	offset := max(p.Offset, 0)
+
+	start := min(offset, len(items))
+	end := min(start+limit, len(items))
+	pg := pagination{Offset: offset, Limit: limit}
+	if end < len(items) {
+		pg.NextPath = fmt.Sprintf("/api/%s?limit=%d&offset=%d", method, limit, end)
+	}
+	return items[start:end], pg
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "status": http.StatusOK, "data": data})
+}
+
+func writePage(w http.ResponseWriter, data any, pg pagination) {
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true, "status": http.StatusOK, "data": data, "pagination": pg})
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	if code == "" {
+		code = strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	writeJSON(w, status, map[string]any{"ok": false, "status": status, "error": code, "message": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// decode reads the JSON body into v. Outline treats an empty body as an
+// empty object.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.ContentLength == 0 {
+		return true
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "validation_error", "Invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package outlinetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"outline-cli/api"
+)
+
+func TestListsArePaginated(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	for i := 0; i < DefaultLimit+5; i++ {
+		srv.AddDocument(api.Document{Title: fmt.Sprintf("Doc %d", i)})
+	}
+
+	var page struct {
+		Data       []api.Document `json:"data"`
+		Pagination pagination     `json:"pagination"`
+	}
+	call := func(body string) {
+		t.Helper()
+		req, err := http.NewRequest("POST", srv.URL+"/api/documents.list", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+APIKey)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		page.Data = nil
+		page.Pagination = pagination{}
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call(`{}`)
+	if len(page.Data) != DefaultLimit || page.Pagination.NextPath == "" {
+		t.Fatalf("expected a full first page with a next path, got %d and %+v", len(page.Data), page.Pagination)
+	}
+
+	call(fmt.Sprintf(`{"offset": %d}`, DefaultLimit))
+	if len(page.Data) != 5 || page.Pagination.NextPath != "" {
+		t.Errorf("expected a final page of 5, got %d and %+v", len(page.Data), page.Pagination)
+	}
+}
@@ -0,0 +1,297 @@
+// Package output renders command results in the formats selected by the
+// global --output, --fields and --format flags.
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported output formats
+const (
+	FormatTable = "table"
+	FormatTSV   = "tsv"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+)
+
+// Options selects how a Printer renders results
+type Options struct {
+	// Format is one of table, tsv, json or yaml
+	Format string
+	// Fields limits output to the named fields, using their JSON names
+	Fields []string
+	// Template is a Go template applied to each item; it overrides Format
+	Template string
+}
+
+// Printer writes structured results to an output stream
+type Printer struct {
+	w    io.Writer
+	opts Options
+	tmpl *template.Template
+}
+
+// New validates opts and returns a Printer writing to w
+func New(w io.Writer, opts Options) (*Printer, error) {
+	if opts.Format == "" {
+		opts.Format = FormatTable
+	}
+
+	switch opts.Format {
+	case FormatTable, FormatTSV, FormatJSON, FormatYAML:
+	default:
+		return nil, fmt.Errorf("invalid output format %q: must be one of table, tsv, json, yaml", opts.Format)
+	}
+
+	p := &Printer{w: w, opts: opts}
+	if opts.Template != "" {
+		tmpl, err := template.New("format").Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parsing format template: %w", err)
+		}
+		p.tmpl = tmpl
+	}
+	return p, nil
+}
+
+// Print renders v, which is a struct or a slice of structs. defaultFields
+// are the columns shown in table and tsv output when --fields is not set.
+func (p *Printer) Print(v any, defaultFields ...string) error {
+	items := toItems(v)
+
+	if p.tmpl != nil {
+		return p.printTemplate(items)
+	}
+
+	fields := p.opts.Fields
+	switch p.opts.Format {
+	case FormatJSON, FormatYAML:
+		var data any = v
+		if len(fields) > 0 {
+			rows, err := project(items, fields)
+			if err != nil {
+				return err
+			}
+			data = rows
+			if !isList(v) {
+				data = rows[0]
+			}
+		}
+		if p.opts.Format == FormatJSON {
+			return p.printJSON(data)
+		}
+		return p.printYAML(data)
+	default:
+		if len(fields) == 0 {
+			fields = defaultFields
+		}
+		if len(fields) == 0 && len(items) > 0 {
+			fields = fieldNames(reflect.TypeOf(items[0]))
+		}
+		return p.printColumns(items, fields, p.opts.Format == FormatTable)
+	}
+}
+
+func (p *Printer) printTemplate(items []any) error {
+	for _, item := range items {
+		if err := p.tmpl.Execute(p.w, item); err != nil {
+			return fmt.Errorf("executing format template: %w", err)
+		}
+		if _, err := fmt.Fprintln(p.w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) printJSON(data any) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(data)
+}
+
+func (p *Printer) printYAML(data any) error {
+	// Round-trip through JSON so YAML keys match the JSON field names
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding output: %w", err)
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return fmt.Errorf("encoding output: %w", err)
+	}
+
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return fmt.Errorf("encoding output: %w", err)
+	}
+	return enc.Close()
+}
+
+func (p *Printer) printColumns(items []any, fields []string, header bool) error {
+	rows, err := columns(items, fields)
+	if err != nil {
+		return err
+	}
+
+	if !header {
+		for _, row := range rows {
+			if _, err := fmt.Fprintln(p.w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	headings := make([]string, len(fields))
+	for i, f := range fields {
+		headings[i] = strings.ToUpper(f)
+	}
+	fmt.Fprintln(tw, strings.Join(headings, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// toItems flattens v into a list of individual results
+func toItems(v any) []any {
+	rv := reflect.ValueOf(v)
+	if !isList(v) {
+		return []any{v}
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+func isList(v any) bool {
+	k := reflect.ValueOf(v).Kind()
+	return k == reflect.Slice || k == reflect.Array
+}
+
+// project reduces each item to a map holding only the requested fields
+func project(items []any, fields []string) ([]map[string]any, error) {
+	rows := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]any, len(fields))
+		for _, f := range fields {
+			val, err := lookup(item, f)
+			if err != nil {
+				return nil, err
+			}
+			row[f] = val.Interface()
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// columns renders the requested fields of each item as strings
+func columns(items []any, fields []string) ([][]string, error) {
+	rows := make([][]string, 0, len(items))
+	for _, item := range items {
+		row := make([]string, len(fields))
+		for i, f := range fields {
+			val, err := lookup(item, f)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = formatValue(val)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// lookup finds the struct field of item whose JSON name is field
+func lookup(item any, field string) (reflect.Value, error) {
+	rv := reflect.Indirect(reflect.ValueOf(item))
+	if rv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("cannot select field %q from %s", field, rv.Kind())
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if name, ok := jsonName(rt.Field(i)); ok && name == field {
+			return rv.Field(i), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("unknown field %q (available: %s)", field, strings.Join(fieldNames(rt), ", "))
+}
+
+// fieldNames lists the JSON names of the exported fields of t
+func fieldNames(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+func formatValue(v reflect.Value) string {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		// Keep each item on a single line so columns stay aligned
+		return strings.Join(strings.Fields(v.String()), " ")
+	case reflect.Slice, reflect.Map, reflect.Struct:
+		raw, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return string(raw)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
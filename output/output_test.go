@@ -0,0 +1,88 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type testDoc struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Text  string `json:"text,omitempty"`
+}
+
+var testDocs = []testDoc{
+	{ID: "doc-1", Title: "First", Text: "one"},
+	{ID: "doc-2", Title: "Second", Text: "two"},
+}
+
+func render(t *testing.T, opts Options, v any, defaults ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	p, err := New(&buf, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Print(v, defaults...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestPrintTable(t *testing.T) {
+	got := render(t, Options{Format: FormatTable}, testDocs, "id", "title")
+	want := "ID     TITLE\ndoc-1  First\ndoc-2  Second\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrintTSVWithFields(t *testing.T) {
+	got := render(t, Options{Format: FormatTSV, Fields: []string{"title", "text"}}, testDocs, "id")
+	want := "First\tone\nSecond\ttwo\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrintJSONWithFields(t *testing.T) {
+	got := render(t, Options{Format: FormatJSON, Fields: []string{"id"}}, testDocs[0])
+	want := "{\n  \"id\": \"doc-1\"\n}\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrintYAML(t *testing.T) {
+	got := render(t, Options{Format: FormatYAML}, testDocs[:1])
+	want := "- id: doc-1\n  text: one\n  title: First\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPrintTemplate(t *testing.T) {
+	got := render(t, Options{Template: "{{.ID}} {{.Title}}"}, testDocs)
+	want := "doc-1 First\ndoc-2 Second\n"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestUnknownField(t *testing.T) {
+	p, err := New(&bytes.Buffer{}, Options{Format: FormatTable, Fields: []string{"nope"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = p.Print(testDocs)
+	if err == nil || !strings.Contains(err.Error(), "available: id, title, text") {
+		t.Errorf("expected unknown field error listing available fields, got %v", err)
+	}
+}
+
+func TestInvalidFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+		t.Error("expected error for invalid format")
+	}
+}
@@ -0,0 +1,141 @@
+package site
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"outline-cli/api"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// noticeStart matches the opening line of an Outline notice block, such as
+// :::info
+var noticeStart = regexp.MustCompile(`^:::\s*(\w+)\s*$`)
+
+// renderer turns Outline Markdown into HTML for pages of one site
+type renderer struct {
+	md      goldmark.Markdown
+	links   map[string]string
+	baseURL string
+}
+
+// newRenderer resolves links to documents through links, which maps
+// document IDs and url-ids to page paths
+func newRenderer(links map[string]string, baseURL string) *renderer {
+	return &renderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		),
+		links:   links,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// render returns the HTML for a page whose root prefix is root, and its
+// plain text for the search index
+func (r *renderer) render(markdown string, root string) (template.HTML, string, error) {
+	src := []byte(normalize(markdown))
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var plain strings.Builder
+	err := ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				plain.WriteByte(' ')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = []byte(r.rewrite(string(n.Destination), root))
+		case *ast.Image:
+			n.Destination = []byte(r.rewrite(string(n.Destination), root))
+		case *ast.Text:
+			plain.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				plain.WriteByte(' ')
+			}
+		case *ast.CodeBlock, *ast.FencedCodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				plain.Write(line.Value(src))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return "", "", err
+	}
+	return template.HTML(buf.String()), strings.Join(strings.Fields(plain.String()), " "), nil
+}
+
+// rewrite points a link at its page in the site. Links to other documents
+// become relative page links, relative links become relative to the page,
+// and paths on the Outline host become absolute.
+func (r *renderer) rewrite(dest string, root string) string {
+	if id, ok := api.LinkedDocumentID(dest); ok {
+		if page, ok := r.links[id]; ok {
+			if u, err := url.Parse(dest); err == nil && u.Fragment != "" {
+				page += "#" + u.Fragment
+			}
+			return root + page
+		}
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(dest, "#") || dest == "" {
+		return dest
+	}
+	if strings.HasPrefix(dest, "/") {
+		if r.baseURL == "" {
+			return dest
+		}
+		return r.baseURL + dest
+	}
+	return root + dest
+}
+
+// normalize converts Outline-specific Markdown to CommonMark: lone
+// backslashes Outline writes for empty paragraphs are dropped and notice
+// blocks become block quotes
+func normalize(markdown string) string {
+	lines := strings.Split(markdown, "\n")
+	out := make([]string, 0, len(lines))
+	inNotice := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == `\`:
+			line = ""
+		case !inNotice && noticeStart.MatchString(trimmed):
+			inNotice = true
+			kind := noticeStart.FindStringSubmatch(trimmed)[1]
+			out = append(out, "> **"+strings.ToUpper(kind[:1])+kind[1:]+"**", ">")
+			continue
+		case inNotice && trimmed == ":::":
+			inNotice = false
+			out = append(out, "")
+			continue
+		}
+		if inNotice {
+			line = "> " + line
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,262 @@
+// Package site renders documents as a static HTML site that can be browsed
+// offline: one page per document, a navigation sidebar following the
+// document hierarchy, relative links between pages and a client-side search
+// index.
+package site
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Page is a document placed in the site
+type Page struct {
+	ID    string
+	URLID string
+	Title string
+	// Path locates the page relative to the site root, such as
+	// runbooks/databases/failover.html
+	Path string
+	// Markdown is the document text. Relative links in it are relative to
+	// the site root.
+	Markdown string
+	Children []*Page
+}
+
+// Section is a top-level group of pages, such as a collection
+type Section struct {
+	Name  string
+	Pages []*Page
+}
+
+// Site is a set of sections to render
+type Site struct {
+	Title    string
+	Sections []*Section
+	// BaseURL makes links to paths on the Outline host, such as attachments
+	// that weren't bundled, absolute
+	BaseURL string
+}
+
+// Static files copied into every site
+const (
+	styleFile       = "style.css"
+	searchFile      = "search.js"
+	searchIndexFile = "search-index.js"
+)
+
+//go:embed static
+var static embed.FS
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}{{if ne .Title .SiteTitle}} - {{.SiteTitle}}{{end}}</title>
+<link rel="stylesheet" href="{{.Root}}` + styleFile + `">
+</head>
+<body data-root="{{.Root}}">
+<nav class="sidebar">
+<a class="site-title" href="{{.Root}}index.html">{{.SiteTitle}}</a>
+<input id="search" type="search" placeholder="Search" autocomplete="off">
+{{.Nav}}
+</nav>
+<main>
+<ul id="search-results" hidden></ul>
+<article id="content">
+<h1>{{.Title}}</h1>
+{{.Content}}
+</article>
+</main>
+<script src="{{.Root}}` + searchIndexFile + `"></script>
+<script src="{{.Root}}` + searchFile + `"></script>
+</body>
+</html>
+`))
+
+type pageData struct {
+	Title     string
+	SiteTitle string
+	Root      string
+	Nav       template.HTML
+	Content   template.HTML
+}
+
+// searchEntry is one page in the search index. Keys are short to keep the
+// index small.
+type searchEntry struct {
+	Title   string `json:"t"`
+	Path    string `json:"p"`
+	Section string `json:"s"`
+	Text    string `json:"x"`
+}
+
+// Build renders the site into dir, which is created if needed
+func (s *Site) Build(dir string) error {
+	links := make(map[string]string)
+	s.walk(func(_ *Section, p *Page) {
+		links[p.ID] = p.Path
+		if p.URLID != "" {
+			links[p.URLID] = p.Path
+		}
+	})
+	r := newRenderer(links, s.BaseURL)
+
+	var index []searchEntry
+	var err error
+	s.walk(func(sec *Section, p *Page) {
+		if err != nil {
+			return
+		}
+		var content template.HTML
+		var text string
+		content, text, err = r.render(p.Markdown, relativeRoot(p.Path))
+		if err != nil {
+			err = fmt.Errorf("rendering %s: %w", p.Title, err)
+			return
+		}
+		index = append(index, searchEntry{Title: p.Title, Path: p.Path, Section: sec.Name, Text: text})
+		err = s.writePage(dir, p.Path, p.Title, content)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.writePage(dir, "index.html", s.Title, s.contents()); err != nil {
+		return err
+	}
+	if err := writeSearchIndex(filepath.Join(dir, searchIndexFile), index); err != nil {
+		return err
+	}
+	for _, name := range []string{styleFile, searchFile} {
+		data, err := static.ReadFile("static/" + name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// walk calls fn for every page, parents before their children
+func (s *Site) walk(fn func(*Section, *Page)) {
+	var visit func(*Section, []*Page)
+	visit = func(sec *Section, pages []*Page) {
+		for _, p := range pages {
+			fn(sec, p)
+			visit(sec, p.Children)
+		}
+	}
+	for _, sec := range s.Sections {
+		visit(sec, sec.Pages)
+	}
+}
+
+func (s *Site) writePage(dir string, pagePath string, title string, content template.HTML) error {
+	root := relativeRoot(pagePath)
+	var buf bytes.Buffer
+	err := pageTemplate.Execute(&buf, pageData{
+		Title:     title,
+		SiteTitle: s.Title,
+		Root:      root,
+		Nav:       s.nav(pagePath, root),
+		Content:   content,
+	})
+	if err != nil {
+		return fmt.Errorf("rendering %s: %w", pagePath, err)
+	}
+
+	filename := filepath.Join(dir, filepath.FromSlash(pagePath))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	if err := os.WriteFile(filename, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", pagePath, err)
+	}
+	return nil
+}
+
+// nav renders the sidebar for the page at current, marking it and keeping
+// its ancestors expanded
+func (s *Site) nav(current string, root string) template.HTML {
+	var sb strings.Builder
+	var list func([]*Page)
+	list = func(pages []*Page) {
+		sb.WriteString("<ul>")
+		for _, p := range pages {
+			class := ""
+			if p.Path == current {
+				class = ` class="current"`
+			}
+			link := fmt.Sprintf(`<a%s href="%s">%s</a>`, class, html.EscapeString(root+p.Path), html.EscapeString(p.Title))
+			if len(p.Children) == 0 {
+				sb.WriteString("<li>" + link + "</li>")
+				continue
+			}
+			open := ""
+			if p.Path == current || strings.HasPrefix(current, strings.TrimSuffix(p.Path, ".html")+"/") {
+				open = " open"
+			}
+			sb.WriteString("<li><details" + open + "><summary>" + link + "</summary>")
+			list(p.Children)
+			sb.WriteString("</details></li>")
+		}
+		sb.WriteString("</ul>")
+	}
+
+	for _, sec := range s.Sections {
+		sb.WriteString(`<section><h2>` + html.EscapeString(sec.Name) + `</h2>`)
+		list(sec.Pages)
+		sb.WriteString(`</section>`)
+	}
+	return template.HTML(sb.String())
+}
+
+// contents renders the body of the index page
+func (s *Site) contents() template.HTML {
+	var sb strings.Builder
+	for _, sec := range s.Sections {
+		sb.WriteString("<h2>" + html.EscapeString(sec.Name) + "</h2><ul>")
+		for _, p := range sec.Pages {
+			sb.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a></li>`, html.EscapeString(p.Path), html.EscapeString(p.Title)))
+		}
+		sb.WriteString("</ul>")
+	}
+	return template.HTML(sb.String())
+}
+
+// writeSearchIndex writes the index as a script rather than JSON so the
+// site also works from file:// URLs, where browsers refuse to fetch files
+func writeSearchIndex(filename string, index []searchEntry) error {
+	if index == nil {
+		index = []searchEntry{}
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return fmt.Errorf("encoding search index: %w", err)
+	}
+	script := append([]byte("window.SEARCH_INDEX = "), data...)
+	script = append(script, ";\n"...)
+	if err := os.WriteFile(filename, script, 0644); err != nil {
+		return fmt.Errorf("writing search index: %w", err)
+	}
+	return nil
+}
+
+// relativeRoot returns the prefix leading from a page back to the site
+// root, such as ../../ for runbooks/databases/failover.html
+func relativeRoot(pagePath string) string {
+	depth := strings.Count(path.Clean(pagePath), "/")
+	return strings.Repeat("../", depth)
+}
@@ -0,0 +1,88 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	r := newRenderer(map[string]string{"Xk3pQ9aB1c": "runbooks/db/failover.html"}, "https://wiki.example.com/")
+
+	tests := []struct {
+		dest string
+		want string
+	}{
+		{"/doc/db-failover-Xk3pQ9aB1c#step-2", "../../runbooks/db/failover.html#step-2"},
+		{"https://wiki.example.com/doc/db-failover-Xk3pQ9aB1c", "../../runbooks/db/failover.html"},
+		{"/doc/unexported-AbCdEfGhIj", "https://wiki.example.com/doc/unexported-AbCdEfGhIj"},
+		{"assets/chart.png", "../../assets/chart.png"},
+		{"#heading", "#heading"},
+		{"https://example.com/page", "https://example.com/page"},
+	}
+	for _, tt := range tests {
+		if got := r.rewrite(tt.dest, "../../"); got != tt.want {
+			t.Errorf("rewrite(%q) = %q, want %q", tt.dest, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	in := "Intro\n\\\n:::warning\nDon't run this in production\n:::\nAfter"
+	want := "Intro\n\n> **Warning**\n>\n> Don't run this in production\n\nAfter"
+	if got := normalize(in); got != want {
+		t.Errorf("normalize:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	child := &Page{ID: "c", URLID: "ChildPage01", Title: "Failover", Path: "runbooks/databases/failover.html", Markdown: "Promote the replica.\n\n![diagram](assets/d.png)"}
+	parent := &Page{ID: "p", URLID: "ParentPg01", Title: "Databases", Path: "runbooks/databases.html",
+		Markdown: "See [failover](/doc/failover-ChildPage01).\n\n<script>alert(1)</script>", Children: []*Page{child}}
+	s := &Site{Title: "Runbooks", Sections: []*Section{{Name: "Runbooks", Pages: []*Page{parent}}}}
+
+	if err := s.Build(dir); err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	page := read("runbooks/databases.html")
+	if !strings.Contains(page, `<a href="../runbooks/databases/failover.html">failover</a>`) {
+		t.Errorf("expected a relative link to the child page, got:\n%s", page)
+	}
+	if strings.Contains(page, "<script>alert") {
+		t.Error("raw HTML in a document was not escaped")
+	}
+	if !strings.Contains(page, `href="../style.css"`) {
+		t.Error("expected the stylesheet to be linked relative to the page")
+	}
+
+	nested := read("runbooks/databases/failover.html")
+	if !strings.Contains(nested, `src="../../assets/d.png"`) {
+		t.Errorf("expected the image relative to the site root, got:\n%s", nested)
+	}
+	if !strings.Contains(nested, `<details open><summary><a href="../../runbooks/databases.html">`) ||
+		!strings.Contains(nested, `class="current"`) {
+		t.Errorf("expected the sidebar to expand the parent and mark the page, got:\n%s", nested)
+	}
+
+	index := read(searchIndexFile)
+	if !strings.Contains(index, `"t":"Failover"`) || !strings.Contains(index, "Promote the replica.") {
+		t.Errorf("expected the page in the search index, got %s", index)
+	}
+	for _, name := range []string{"index.html", styleFile, searchFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+}
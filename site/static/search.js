@@ -0,0 +1,72 @@
+// Client-side search over window.SEARCH_INDEX, written by the exporter as
+// [{t: title, p: path, s: section, x: text}]
+(function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("search-results");
+  var content = document.getElementById("content");
+  var root = document.body.getAttribute("data-root") || "";
+  var index = window.SEARCH_INDEX || [];
+
+  function snippet(text, term) {
+    var at = text.toLowerCase().indexOf(term);
+    if (at < 0) {
+      return text.slice(0, 160);
+    }
+    var start = Math.max(0, at - 60);
+    return (start > 0 ? "…" : "") + text.slice(start, at + 100) + "…";
+  }
+
+  function search(query) {
+    var terms = query.toLowerCase().split(/\s+/).filter(Boolean);
+    var matches = [];
+    index.forEach(function (entry) {
+      var title = entry.t.toLowerCase();
+      var text = entry.x.toLowerCase();
+      var score = 0;
+      for (var i = 0; i < terms.length; i++) {
+        if (title.indexOf(terms[i]) >= 0) {
+          score += 10;
+        } else if (text.indexOf(terms[i]) >= 0) {
+          score += 1;
+        } else {
+          return;
+        }
+      }
+      matches.push({ entry: entry, score: score });
+    });
+    matches.sort(function (a, b) { return b.score - a.score; });
+    return matches.slice(0, 50).map(function (m) { return m.entry; });
+  }
+
+  function show(query) {
+    results.textContent = "";
+    if (query.trim().length < 2) {
+      results.hidden = true;
+      content.hidden = false;
+      return;
+    }
+    var found = search(query);
+    var term = query.toLowerCase().split(/\s+/).filter(Boolean)[0];
+    if (found.length === 0) {
+      var none = document.createElement("li");
+      none.textContent = "No results";
+      results.appendChild(none);
+    }
+    found.forEach(function (entry) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = root + entry.p;
+      a.textContent = entry.t;
+      var section = document.createElement("div");
+      section.className = "snippet";
+      section.textContent = entry.s + " · " + snippet(entry.x, term);
+      li.appendChild(a);
+      li.appendChild(section);
+      results.appendChild(li);
+    });
+    results.hidden = false;
+    content.hidden = true;
+  }
+
+  input.addEventListener("input", function () { show(input.value); });
+})();
@@ -0,0 +1,175 @@
+// Package workspace maps Outline documents to local paths. IDs are
+// validated and titles slugified before they become file names, and every
+// path handed out is checked to stay inside the workspace root.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ext is the extension of document files
+const Ext = ".md"
+
+// MaxNameBytes bounds slugs well below common file system name limits,
+// leaving room for a disambiguating suffix and the extension
+const MaxNameBytes = 100
+
+var (
+	// ErrInvalidID is returned for strings that cannot be an Outline ID
+	ErrInvalidID = errors.New("invalid document ID")
+	// ErrOutsideRoot is returned for paths that escape the workspace root
+	ErrOutsideRoot = errors.New("path is outside the workspace")
+)
+
+// Outline document IDs are UUIDs and url-ids are short alphanumeric
+// strings, optionally prefixed by a title slug. Nothing else may be used
+// to build a file name.
+var validID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,127}$`)
+
+// reserved lists names Windows treats as devices regardless of extension
+var reserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// ValidateID checks that id is shaped like an Outline ID or url-id
+func ValidateID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("%w %q", ErrInvalidID, id)
+	}
+	return nil
+}
+
+// DocumentFile returns the file name of a document named by its ID
+func DocumentFile(id string) (string, error) {
+	if err := ValidateID(id); err != nil {
+		return "", err
+	}
+	return id + Ext, nil
+}
+
+// IDFromFile reverses DocumentFile for a path whose base name is <id>.md
+func IDFromFile(name string) (string, error) {
+	id, ok := strings.CutSuffix(filepath.Base(name), Ext)
+	if !ok {
+		return "", fmt.Errorf("%s is not a %s file", name, Ext)
+	}
+	if err := ValidateID(id); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return id, nil
+}
+
+// Slugify turns a title into a portable file name. Letters and digits in
+// any script are kept, whitespace becomes '-', everything else is dropped.
+// The result never starts with a dot, is never a reserved device name and
+// fits in MaxNameBytes.
+func Slugify(title string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsMark(r), r == '-', r == '_', r == '.':
+			return r
+		case unicode.IsSpace(r):
+			return '-'
+		default:
+			return -1
+		}
+	}, strings.TrimSpace(title))
+
+	if len(slug) > MaxNameBytes {
+		cut := MaxNameBytes
+		for cut > 0 && !utf8.RuneStart(slug[cut]) {
+			cut--
+		}
+		slug = slug[:cut]
+	}
+
+	slug = strings.Trim(slug, ".-")
+	if slug == "" {
+		return "untitled"
+	}
+
+	base, _, _ := strings.Cut(slug, ".")
+	if reserved[strings.ToUpper(base)] {
+		slug = "_" + slug
+	}
+	return slug
+}
+
+// Root is a directory that local paths must stay inside
+type Root struct {
+	dir string
+}
+
+// NewRoot returns a root at dir, which is made absolute with symlinks
+// resolved
+func NewRoot(dir string) (*Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("resolving %s: %w", dir, err)
+	}
+	return &Root{dir: abs}, nil
+}
+
+// Dir returns the absolute root directory
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Resolve turns a slash-separated path relative to the root into an
+// absolute path. It fails if the path is absolute, climbs out with "..",
+// or passes through a symlink that leads outside the root.
+func (r *Root) Resolve(rel string) (string, error) {
+	if rel == "" || filepath.IsAbs(filepath.FromSlash(rel)) || strings.HasPrefix(rel, "/") || filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("%w: %q", ErrOutsideRoot, rel)
+	}
+
+	full := filepath.Join(r.dir, filepath.FromSlash(rel))
+	if !r.contains(full) {
+		return "", fmt.Errorf("%w: %q", ErrOutsideRoot, rel)
+	}
+
+	// Check where the deepest existing ancestor really lives
+	existing := full
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !r.contains(resolved) {
+				return "", fmt.Errorf("%w: %q", ErrOutsideRoot, rel)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("resolving %s: %w", rel, err)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing || !r.contains(parent) {
+			break
+		}
+		existing = parent
+	}
+	return full, nil
+}
+
+func (r *Root) contains(path string) bool {
+	rel, err := filepath.Rel(r.dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
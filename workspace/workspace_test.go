@@ -0,0 +1,101 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidateID(t *testing.T) {
+	valid := []string{
+		"8f2de8e6-a423-4960-8802-18c0da301989",
+		"Xk3pQ9aB1c",
+		"runbook-db-failover-Xk3pQ9aB1c",
+	}
+	for _, id := range valid {
+		if err := ValidateID(id); err != nil {
+			t.Errorf("ValidateID(%q) = %v", id, err)
+		}
+	}
+
+	invalid := []string{"", "..", "../../etc/foo", "a/b", `a\b`, "-rf", ".hidden", "a b", strings.Repeat("a", 200)}
+	for _, id := range invalid {
+		if err := ValidateID(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("ValidateID(%q) = %v, want ErrInvalidID", id, err)
+		}
+	}
+}
+
+func TestIDFromFile(t *testing.T) {
+	id, err := IDFromFile(filepath.Join("docs", "Xk3pQ9aB1c.md"))
+	if err != nil || id != "Xk3pQ9aB1c" {
+		t.Errorf("IDFromFile = %q, %v", id, err)
+	}
+	if _, err := IDFromFile("notes.txt"); err == nil {
+		t.Error("expected non-Markdown file to be rejected")
+	}
+	if _, err := IDFromFile("my notes.md"); err == nil {
+		t.Error("expected file not named after an ID to be rejected")
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Database Failover", "Database-Failover"},
+		{"  ../../etc/passwd  ", "etcpasswd"},
+		{"Café au lait", "Café-au-lait"},
+		{"日本語のページ", "日本語のページ"},
+		{"a/b\\c:d*e?f", "abcdef"},
+		{".hidden", "hidden"},
+		{"CON", "_CON"},
+		{"nul.txt", "_nul.txt"},
+		{"???", "untitled"},
+		{"", "untitled"},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.title); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+
+	long := Slugify(strings.Repeat("é", 200))
+	if len(long) > MaxNameBytes || !utf8.ValidString(long) {
+		t.Errorf("expected a valid slug of at most %d bytes, got %d bytes", MaxNameBytes, len(long))
+	}
+}
+
+func TestRootResolve(t *testing.T) {
+	dir := t.TempDir()
+	root, err := NewRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := root.Resolve("Runbooks/Failover.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(root.Dir(), "Runbooks", "Failover.md"); got != want {
+		t.Errorf("Resolve = %q, want %q", got, want)
+	}
+
+	for _, rel := range []string{"", "/etc/passwd", "../outside.md", "a/../../outside.md"} {
+		if _, err := root.Resolve(rel); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("Resolve(%q) = %v, want ErrOutsideRoot", rel, err)
+		}
+	}
+
+	// A symlink pointing out of the root doesn't make a way out
+	if err := os.Symlink(t.TempDir(), filepath.Join(dir, "escape")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if _, err := root.Resolve("escape/file.md"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("Resolve through symlink = %v, want ErrOutsideRoot", err)
+	}
+}